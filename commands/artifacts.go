@@ -0,0 +1,334 @@
+package commands
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/jfrog/jfrog-cli-core/artifactory/spec"
+	rtutils "github.com/jfrog/jfrog-cli-core/artifactory/utils"
+	"github.com/jfrog/jfrog-cli-core/utils/config"
+	distributionServicesUtils "github.com/jfrog/jfrog-client-go/distribution/services/utils"
+	"github.com/jfrog/jfrog-client-go/utils/errorutils"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// bundleArtifact is something the release bundle is expected to contain, such
+// as a Docker image or a package. It's listed in the report by name, and found
+// if any of its file spec patterns match. Artifacts which can't be described by
+// patterns alone, such as the result of an AQL query, list their file spec
+// entries instead, and are found if searching for them finds anything.
+// Distribution adds addedProps to the artifact in the bundle, and stores it in
+// the target repository of its source repository on the edge nodes.
+// An artifact created from an image reference or a chart archive keeps it as
+// its source, along with the charts it's in the bundle for.
+type bundleArtifact struct {
+	name        string
+	packageType string
+	source      string
+	origins     []artifactOrigin
+	patterns    []string
+	files       []spec.File
+	addedProps  []releaseBundleProp
+	targetRepos map[string]string
+}
+
+// bundleOptions are the options shared by all the commands creating a release
+// bundle, which aren't part of distributionServicesUtils.ReleaseBundleParams.
+type bundleOptions struct {
+	repoMappings  []repoMapping
+	originProps   bool
+	props         []releaseBundleProp
+	verifyDigests bool
+	platforms     []string
+	referrers     bool
+	signatures    *signatureVerifier
+	policy        *bundlePolicy
+	// The vulnerability gate is enabled by a severity threshold.
+	vulnerabilityThreshold string
+	scanUrl                string
+	vulnerabilityNotes     bool
+	// The SBOM of the bundle is uploaded to sbomPath, if set.
+	sbomPath string
+	// The artifacts are searched for resolveWorkers at a time.
+	resolveWorkers int
+	// Charts and search results are read from cache, unless it's nil.
+	cache *fileCache
+	// Chart archives larger than maxChartSize bytes aren't downloaded. The
+	// SHA-256 checksums of the downloaded ones are reported, by path.
+	maxChartSize   int64
+	chartChecksums map[string]string
+	// The reports are printed to out, or to the standard output if not set.
+	out io.Writer
+}
+
+// specFileJson is a spec.File as written in a file spec. Its AQL query is an
+// object, as spec.File expects when reading a file spec.
+type specFileJson struct {
+	Aql          json.RawMessage `json:"aql,omitempty"`
+	Pattern      string          `json:"pattern,omitempty"`
+	Exclusions   []string        `json:"exclusions,omitempty"`
+	Target       string          `json:"target,omitempty"`
+	Props        string          `json:"props,omitempty"`
+	ExcludeProps string          `json:"excludeProps,omitempty"`
+	Build        string          `json:"build,omitempty"`
+	Bundle       string          `json:"bundle,omitempty"`
+	Recursive    string          `json:"recursive,omitempty"`
+}
+
+// newBundleArtifact creates an artifact that is stored at path, either directly
+// under the root of repo or in one of its folders.
+func newBundleArtifact(name, repo, path string) bundleArtifact {
+	return bundleArtifact{name: name, patterns: []string{repo + "/" + path, repo + "/*/" + path}}
+}
+
+// specFiles returns the file spec entries of the artifact, one per pattern,
+// followed by its other entries.
+func (ba bundleArtifact) specFiles() []spec.File {
+	files := make([]spec.File, 0)
+	for _, pattern := range ba.patterns {
+		files = append(files, mapSpecFile(spec.File{Pattern: pattern}, ba.targetRepos))
+	}
+	for _, file := range ba.files {
+		files = append(files, mapSpecFile(file, ba.targetRepos))
+	}
+	return files
+}
+
+func createSpecFiles(artifacts []bundleArtifact) *spec.SpecFiles {
+	specfiles := &spec.SpecFiles{Files: make([]spec.File, 0)}
+	for _, artifact := range artifacts {
+		specfiles.Files = append(specfiles.Files, artifact.specFiles()...)
+	}
+	return specfiles
+}
+
+// serializeSpecFiles writes a file spec, as read by spec.CreateSpecFromFile.
+func serializeSpecFiles(specfiles *spec.SpecFiles) string {
+	entries := make([]specFileJson, 0)
+	for _, file := range specfiles.Files {
+		entry := specFileJson{Pattern: file.Pattern, Exclusions: file.Exclusions, Target: file.Target, Props: file.Props,
+			ExcludeProps: file.ExcludeProps, Build: file.Build, Bundle: file.Bundle, Recursive: file.Recursive}
+		if file.Aql.ItemsFind != "" {
+			entry.Aql = json.RawMessage("{\"items.find\":" + file.Aql.ItemsFind + "}")
+		}
+		entries = append(entries, entry)
+	}
+	content, _ := json.Marshal(struct {
+		Files []specFileJson `json:"files"`
+	}{entries})
+	return string(content)
+}
+
+func createBundleAndReport(rtDetails *config.ArtifactoryDetails, params distributionServicesUtils.ReleaseBundleParams, options bundleOptions, expected []bundleArtifact, dryRun bool) error {
+	if len(expected) == 0 {
+		return errorutils.CheckError(errors.New("Found nothing to put in the release bundle."))
+	}
+	out := options.out
+	if out == nil {
+		out = os.Stdout
+	}
+	printLicenses(out, expected)
+	if options.policy != nil {
+		if err := reportPolicyViolations(out, options.policy.evaluate(expected)); err != nil {
+			return err
+		}
+	}
+	expected = applyRepoMappings(expected, options.repoMappings)
+	expected = applyBundleProps(expected, options)
+	read := func(path string) (io.ReadCloser, error) {
+		return readFileFromArtifactory(rtDetails, path)
+	}
+	search := withCache(withRetries(func(specfiles *spec.SpecFiles) ([]rtutils.SearchResult, error) {
+		return searchExisting(rtDetails, specfiles)
+	}), options.cache, rtDetails.Url)
+	workers := options.resolveWorkers
+	if workers == 0 {
+		workers, _ = strconv.Atoi(defaultResolveWorkers)
+	}
+	results, err := resolveArtifacts(expected, search, workers)
+	if err != nil {
+		return err
+	}
+	expected, platforms, err := expandManifestLists(expected, results, read, options.platforms)
+	if err != nil {
+		return err
+	}
+	if len(platforms) > 0 {
+		results, err = resolveArtifacts(expected, search, workers)
+		if err != nil {
+			return err
+		}
+	}
+	actual := resultPaths(results)
+	digests, err := resolveImageDigests(expected, results, read)
+	if err != nil {
+		return err
+	}
+	if options.signatures != nil {
+		checks, err := verifyImageSignatures(expected, actual, digests, search, read, options.signatures)
+		if err != nil {
+			return err
+		}
+		if err := reportSignatures(out, checks); err != nil {
+			return err
+		}
+	}
+	if options.vulnerabilityThreshold != "" {
+		scanUrl := options.scanUrl
+		if scanUrl == "" {
+			scanUrl = defaultScanUrl(rtDetails.Url)
+		}
+		findings, err := scanVulnerabilities(expected, newXrayScanner(rtDetails, scanUrl))
+		if err != nil {
+			return err
+		}
+		if options.vulnerabilityNotes {
+			params.ReleaseNotes = addFindingsToReleaseNotes(params.ReleaseNotes, findings)
+		}
+		if err := reportVulnerabilities(out, findings, options.vulnerabilityThreshold); err != nil {
+			return err
+		}
+	}
+	expected = pinImageDigests(expected, digests)
+	if options.sbomPath != "" {
+		sbom, err := uploadSbom(rtDetails, params.Name, params.Version, expected, digests, options.sbomPath, dryRun)
+		if err != nil {
+			return err
+		}
+		expected = append(expected, applyBundleProps(applyRepoMappings([]bundleArtifact{sbom}, options.repoMappings), options)...)
+		if !dryRun {
+			actual = append(actual, sbom.source)
+		}
+	}
+	if options.referrers {
+		referrers, referrerResults, err := findReferrers(expected, actual, digests, search, read)
+		if err != nil {
+			return err
+		}
+		expected = append(expected, referrers...)
+		actual = append(actual, resultPaths(referrerResults)...)
+	}
+	if options.verifyDigests && !dryRun {
+		err = createVerifiedReleaseBundle(rtDetails, params, expected, digests)
+	} else {
+		err = createReleaseBundle(rtDetails, params, expected, dryRun)
+	}
+	if err != nil {
+		return err
+	}
+	found := make([]string, 0)
+	missing := make([]string, 0)
+	for _, artifact := range expected {
+		exists := artifact.foundIn(actual)
+		if len(artifact.files) > 0 {
+			results, err := search(&spec.SpecFiles{Files: artifact.files})
+			if err != nil {
+				return err
+			}
+			exists = exists || len(results) > 0
+		}
+		if !exists {
+			missing = append(missing, artifact.name)
+			continue
+		}
+		included, multiArch := platforms[artifact.name]
+		if multiArch && len(included) == 0 {
+			missing = append(missing, artifact.name+" (none of the selected platforms)")
+			continue
+		}
+		line := artifact.name
+		if digest, ok := digests[artifact.name]; ok {
+			line = line + "@" + digest
+		}
+		if multiArch {
+			line = line + " (" + strings.Join(included, ", ") + ")"
+		}
+		for chartPath, checksum := range options.chartChecksums {
+			if artifact.packageType == "helm" && artifact.foundIn([]string{strings.TrimPrefix(chartPath, "/")}) {
+				line = line + " sha256:" + checksum
+				break
+			}
+		}
+		found = append(found, line)
+	}
+	printReport(out, "Found:", found, missing)
+	return nil
+}
+
+// mergeArtifacts concatenates lists of artifacts, dropping the ones which are
+// already listed after adding their origins to those of the listed ones.
+func mergeArtifacts(lists ...[]bundleArtifact) []bundleArtifact {
+	merged := make([]bundleArtifact, 0)
+	seen := map[string]int{}
+	for _, artifacts := range lists {
+		for _, artifact := range artifacts {
+			key := serializeSpecFiles(createSpecFiles([]bundleArtifact{artifact}))
+			i, ok := seen[key]
+			if !ok {
+				seen[key] = len(merged)
+				merged = append(merged, artifact)
+				continue
+			}
+			origins := originIndex{}
+			for _, origin := range append(append([]artifactOrigin{}, merged[i].origins...), artifact.origins...) {
+				origins.add(key, origin)
+			}
+			merged[i].origins = origins[key]
+		}
+	}
+	return merged
+}
+
+// printReport prints the names of the artifacts which were found, under
+// heading, and of those which are missing, to out.
+func printReport(out io.Writer, heading string, found, missing []string) {
+	fmt.Fprintln(out, heading)
+	for _, line := range found {
+		fmt.Fprintln(out, "- "+line)
+	}
+	fmt.Fprintln(out, "Missing:")
+	if len(missing) <= 0 {
+		missing = append(missing, "none")
+	}
+	for _, line := range missing {
+		fmt.Fprintln(out, "- "+line)
+	}
+}
+
+func (ba bundleArtifact) foundIn(paths []string) bool {
+	return len(ba.matches(paths)) > 0
+}
+
+// matches returns the paths matching any of the patterns of the artifact.
+func (ba bundleArtifact) matches(paths []string) []string {
+	matchers := make([]*regexp.Regexp, 0)
+	for _, pattern := range ba.patterns {
+		matchers = append(matchers, patternToRegexp(pattern))
+	}
+	matched := make([]string, 0)
+	for _, path := range paths {
+		for _, matcher := range matchers {
+			if matcher.MatchString(path) {
+				matched = append(matched, path)
+				break
+			}
+		}
+	}
+	return matched
+}
+
+// patternToRegexp converts a file spec pattern to a regular expression. As in
+// file specs, a pattern ending with a slash matches everything in that folder.
+func patternToRegexp(pattern string) *regexp.Regexp {
+	if strings.HasSuffix(pattern, "/") {
+		pattern = pattern + "*"
+	}
+	expr := regexp.QuoteMeta(pattern)
+	expr = strings.ReplaceAll(expr, "\\*", ".*")
+	expr = strings.ReplaceAll(expr, "\\?", ".")
+	return regexp.MustCompile("^" + expr + "$")
+}
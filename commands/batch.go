@@ -0,0 +1,300 @@
+package commands
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/ghodss/yaml"
+	rtcommands "github.com/jfrog/jfrog-cli-core/artifactory/commands"
+	"github.com/jfrog/jfrog-cli-core/plugins/components"
+	"github.com/jfrog/jfrog-cli-core/utils/config"
+	distributionServicesUtils "github.com/jfrog/jfrog-client-go/distribution/services/utils"
+	"github.com/jfrog/jfrog-client-go/utils/errorutils"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const defaultBatchWorkers = "4"
+
+type BatchCommand struct {
+	rtDetails            *config.ArtifactoryDetails
+	releaseBundlesParams distributionServicesUtils.ReleaseBundleParams
+	bundleOptions        bundleOptions
+	batchFilePath        string
+	workers              int
+	dryRun               bool
+}
+
+// batchFile lists the release bundles to generate from charts, as from-chart
+// would. The release bundle flags of the command apply to all of them.
+type batchFile struct {
+	DockerRepo string       `json:"dockerRepo"`
+	Bundles    []batchEntry `json:"bundles"`
+}
+
+// batchEntry is a release bundle of a batch file, with the options of
+// from-chart which differ from one chart to another.
+type batchEntry struct {
+	Name      string `json:"name"`
+	Version   string `json:"version"`
+	ChartPath string `json:"chartPath"`
+	// DockerRepo defaults to the Docker repository of the batch file.
+	DockerRepo       string `json:"dockerRepo"`
+	Description      string `json:"description"`
+	Extend           string `json:"extend"`
+	Relocate         string `json:"relocate"`
+	RelocatedVersion string `json:"relocatedVersion"`
+	EdgeValues       string `json:"edgeValues"`
+	EdgeValuesPath   string `json:"edgeValuesPath"`
+	// Props are added to the properties of the --props flag.
+	Props string `json:"props"`
+}
+
+// batchRunner generates the release bundle of an entry, printing its report
+// to out.
+type batchRunner func(entry batchEntry, out io.Writer) error
+
+// batchResult is the outcome of generating the release bundle of an entry.
+type batchResult struct {
+	entry batchEntry
+	err   error
+}
+
+func GetBatchCommand() components.Command {
+	return components.Command{
+		Name:        "batch",
+		Description: "Generate release bundles from many Helm charts at once, as listed in a batch file.",
+		Aliases:     []string{"b"},
+		Arguments:   []components.Argument{},
+		Flags:       getBatchFlags(),
+		EnvVars:     []components.EnvVar{},
+		Action: func(c *components.Context) error {
+			return batchCmd(c)
+		},
+	}
+}
+
+func getBatchFlags() []components.Flag {
+	flags := append(getArtifactoryFlags(),
+		components.StringFlag{
+			Name:        "batch-file",
+			Description: "Local YAML file listing the name, version, chart path, Docker repository and options of each release bundle.",
+			Mandatory:   true,
+		},
+		components.StringFlag{
+			Name:         "workers",
+			Description:  "The number of release bundles generated concurrently.",
+			DefaultValue: defaultBatchWorkers,
+		})
+	return append(flags, getReleaseBundleFlags()...)
+}
+
+func batchCmd(c *components.Context) error {
+	batchFilePath := c.GetStringFlagValue("batch-file")
+	if !(len(c.Arguments) == 0 && batchFilePath != "") {
+		return errors.New("Wrong number of arguments.")
+	}
+	workers, err := parseWorkers(c.GetStringFlagValue("workers"), defaultBatchWorkers)
+	if err != nil {
+		return err
+	}
+	params, err := createReleaseBundleCreateUpdateParams(c, "", "")
+	if err != nil {
+		return err
+	}
+	options, err := createBundleOptions(c)
+	if err != nil {
+		return err
+	}
+	if strings.Contains(strings.TrimPrefix(options.sbomPath, "/"), "/") && !strings.HasSuffix(options.sbomPath, "/") {
+		return errorutils.CheckError(errors.New("With batch, --sbom must be a repository or a folder, ending with a slash, so that each release bundle has its own SBOM."))
+	}
+	rtDetails, err := createArtifactoryDetailsByFlags(c)
+	if err != nil {
+		return err
+	}
+	batchCmd := NewBatchCommand()
+	batchCmd.SetRtDetails(rtDetails).SetReleaseBundleCreateParams(params).SetBundleOptions(options).SetBatchFilePath(batchFilePath).SetWorkers(workers).SetDryRun(c.GetBoolFlagValue("dry-run"))
+	return rtcommands.Exec(batchCmd)
+}
+
+// parseWorkers parses a number of workers, which defaults to defaultWorkers.
+func parseWorkers(workers, defaultWorkers string) (int, error) {
+	if workers == "" {
+		workers = defaultWorkers
+	}
+	count, err := strconv.Atoi(workers)
+	if err != nil || count < 1 {
+		return 0, errorutils.CheckError(errors.New("The number of workers must be a positive integer, got " + workers + "."))
+	}
+	return count, nil
+}
+
+func NewBatchCommand() *BatchCommand {
+	return &BatchCommand{}
+}
+
+func (bc *BatchCommand) SetRtDetails(rtDetails *config.ArtifactoryDetails) *BatchCommand {
+	bc.rtDetails = rtDetails
+	return bc
+}
+
+func (bc *BatchCommand) SetReleaseBundleCreateParams(params distributionServicesUtils.ReleaseBundleParams) *BatchCommand {
+	bc.releaseBundlesParams = params
+	return bc
+}
+
+func (bc *BatchCommand) SetBundleOptions(options bundleOptions) *BatchCommand {
+	bc.bundleOptions = options
+	return bc
+}
+
+func (bc *BatchCommand) SetBatchFilePath(batchFilePath string) *BatchCommand {
+	bc.batchFilePath = batchFilePath
+	return bc
+}
+
+func (bc *BatchCommand) SetWorkers(workers int) *BatchCommand {
+	bc.workers = workers
+	return bc
+}
+
+func (bc *BatchCommand) SetDryRun(dryRun bool) *BatchCommand {
+	bc.dryRun = dryRun
+	return bc
+}
+
+func (bc *BatchCommand) Run() error {
+	batch, err := readBatchFile(bc.batchFilePath)
+	if err != nil {
+		return err
+	}
+	results := runBatch(batch.Bundles, bc.workers, bc.runEntry, os.Stdout)
+	return reportBatch(os.Stdout, results)
+}
+
+// runEntry generates the release bundle of entry as from-chart does.
+func (bc *BatchCommand) runEntry(entry batchEntry, out io.Writer) error {
+	params := bc.releaseBundlesParams
+	params.Name = entry.Name
+	params.Version = entry.Version
+	if entry.Description != "" {
+		params.Description = entry.Description
+	}
+	options := bc.bundleOptions
+	options.out = out
+	if entry.Props != "" {
+		props, err := parseProps(entry.Props)
+		if err != nil {
+			return err
+		}
+		options.props = mergeProps(options.props, props...)
+	}
+	translateChartCmd := NewTranslateChartCommand()
+	translateChartCmd.SetRtDetails(bc.rtDetails).SetReleaseBundleCreateParams(params).SetBundleOptions(options).SetSourceChartPath(entry.ChartPath).SetDockerRepo(entry.DockerRepo).SetExtendBundle(entry.Extend).SetRelocate(entry.Relocate).SetRelocatedVersion(entry.RelocatedVersion).SetEdgeValues(entry.EdgeValues).SetEdgeValuesPath(entry.EdgeValuesPath).SetDryRun(bc.dryRun)
+	return translateChartCmd.Run()
+}
+
+func (bc *BatchCommand) RtDetails() (*config.ArtifactoryDetails, error) {
+	return bc.rtDetails, nil
+}
+
+func (bc *BatchCommand) CommandName() string {
+	return "rt_translate_batch"
+}
+
+// readBatchFile reads a batch file, setting the Docker repository of each entry
+// and checking that no entry lacks a mandatory field or repeats another one.
+func readBatchFile(path string) (*batchFile, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errorutils.CheckError(err)
+	}
+	batch := &batchFile{}
+	if err = yaml.Unmarshal(content, batch); err != nil {
+		return nil, errorutils.CheckError(err)
+	}
+	if len(batch.Bundles) == 0 {
+		return nil, errorutils.CheckError(errors.New("The batch file lists no release bundles."))
+	}
+	seen := map[string]bool{}
+	for i := range batch.Bundles {
+		entry := &batch.Bundles[i]
+		if entry.DockerRepo == "" {
+			entry.DockerRepo = batch.DockerRepo
+		}
+		missing := make([]string, 0)
+		for _, field := range []struct{ name, value string }{{"name", entry.Name}, {"version", entry.Version}, {"chartPath", entry.ChartPath}, {"dockerRepo", entry.DockerRepo}} {
+			if field.value == "" {
+				missing = append(missing, field.name)
+			}
+		}
+		if len(missing) > 0 {
+			return nil, errorutils.CheckError(errors.New("Release bundle #" + strconv.Itoa(i+1) + " of the batch file doesn't set " + strings.Join(missing, ", ") + "."))
+		}
+		if seen[entry.Name+"/"+entry.Version] {
+			return nil, errorutils.CheckError(errors.New("The release bundle " + entry.Name + "/" + entry.Version + " is listed more than once in the batch file."))
+		}
+		seen[entry.Name+"/"+entry.Version] = true
+	}
+	return batch, nil
+}
+
+// runBatch generates the release bundles of entries with run, workers at a
+// time. The report of each bundle is printed to out as a whole once the bundle
+// is done, and the results are returned in the order of entries.
+func runBatch(entries []batchEntry, workers int, run batchRunner, out io.Writer) []batchResult {
+	results := make([]batchResult, len(entries))
+	indexes := make(chan int)
+	var printing sync.Mutex
+	var wg sync.WaitGroup
+	for w := 0; w < workers && w < len(entries); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				report := &bytes.Buffer{}
+				err := run(entries[i], report)
+				results[i] = batchResult{entry: entries[i], err: err}
+				printing.Lock()
+				fmt.Fprintln(out, entries[i].Name+"/"+entries[i].Version+":")
+				out.Write(report.Bytes())
+				if err != nil {
+					fmt.Fprintln(out, "Error: "+err.Error())
+				}
+				printing.Unlock()
+			}
+		}()
+	}
+	for i := range entries {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+	return results
+}
+
+// reportBatch prints the outcome of each release bundle, and fails if any of
+// them failed.
+func reportBatch(out io.Writer, results []batchResult) error {
+	fmt.Fprintln(out, "Summary:")
+	failed := 0
+	for _, result := range results {
+		line := result.entry.Name + "/" + result.entry.Version + ": "
+		if result.err != nil {
+			failed++
+			line = line + "failed: " + result.err.Error()
+		} else {
+			line = line + "succeeded"
+		}
+		fmt.Fprintln(out, "- "+line)
+	}
+	if failed > 0 {
+		return errorutils.CheckError(errors.New(strconv.Itoa(failed) + " of " + strconv.Itoa(len(results)) + " release bundles failed."))
+	}
+	return nil
+}
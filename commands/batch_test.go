@@ -0,0 +1,115 @@
+package commands
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestReadBatchFile(t *testing.T) {
+	batch, err := readBatchFile("testdata/batch/bundles.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(batch.Bundles) != 2 {
+		t.Fatalf("Expected 2 release bundles, got %d", len(batch.Bundles))
+	}
+	jcr, autoscaler := batch.Bundles[0], batch.Bundles[1]
+	if jcr.Name != "artifactory-jcr" || jcr.Version != "2.2.0" || jcr.DockerRepo != "docker-local" || jcr.Props != "team=platform" {
+		t.Errorf("Unexpected release bundle %+v", jcr)
+	}
+	if autoscaler.DockerRepo != "docker-remote" || autoscaler.Relocate != "edge.example.com/docker-edge" {
+		t.Errorf("Unexpected release bundle %+v", autoscaler)
+	}
+
+	dir, err := ioutil.TempDir("", "batch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	invalid := map[string]string{
+		"bundles:\n  - name: app\n    version: 1.0.0\n    chartPath: helm-local/app-1.0.0.tgz\n":                                                                                                 "doesn't set dockerRepo",
+		"dockerRepo: docker\nbundles:\n  - name: app\n    version: 1.0.0\n    chartPath: helm-local/app-1.0.0.tgz\n  - name: app\n    version: 1.0.0\n    chartPath: helm-local/app-1.0.1.tgz\n": "listed more than once",
+		"dockerRepo: docker\n": "lists no release bundles",
+	}
+	for content, message := range invalid {
+		path := filepath.Join(dir, "bundles.yaml")
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := readBatchFile(path); err == nil || !strings.Contains(err.Error(), message) {
+			t.Errorf("Expected an error containing %q, got %v", message, err)
+		}
+	}
+}
+
+func TestRunBatch(t *testing.T) {
+	entries := make([]batchEntry, 0)
+	for i := 0; i < 10; i++ {
+		entries = append(entries, batchEntry{Name: fmt.Sprintf("chart%d", i), Version: "1.0.0"})
+	}
+	var mutex sync.Mutex
+	running, maxRunning := 0, 0
+	run := func(entry batchEntry, out io.Writer) error {
+		mutex.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		mutex.Unlock()
+		fmt.Fprintln(out, "Found:")
+		time.Sleep(10 * time.Millisecond)
+		fmt.Fprintln(out, "- "+entry.Name)
+		mutex.Lock()
+		running--
+		mutex.Unlock()
+		if entry.Name == "chart3" {
+			return errors.New("chart not found")
+		}
+		return nil
+	}
+	out := &bytes.Buffer{}
+	results := runBatch(entries, 3, run, out)
+	if maxRunning < 2 || maxRunning > 3 {
+		t.Errorf("Expected at most 3 release bundles generated at once, got %d", maxRunning)
+	}
+	for i, result := range results {
+		if result.entry.Name != entries[i].Name || (result.err != nil) != (i == 3) {
+			t.Errorf("Unexpected result %d: %+v", i, result)
+		}
+	}
+	for _, entry := range entries {
+		if !strings.Contains(out.String(), entry.Name+"/1.0.0:\nFound:\n- "+entry.Name+"\n") {
+			t.Errorf("Expected the report of %s in one piece, got:\n%s", entry.Name, out.String())
+		}
+	}
+
+	summary := &bytes.Buffer{}
+	err := reportBatch(summary, results)
+	if err == nil || err.Error() != "1 of 10 release bundles failed." {
+		t.Errorf("Expected the batch to fail, got %v", err)
+	}
+	if !strings.Contains(summary.String(), "- chart3/1.0.0: failed: chart not found\n- chart4/1.0.0: succeeded\n") {
+		t.Errorf("Unexpected summary:\n%s", summary.String())
+	}
+	if err := reportBatch(ioutil.Discard, results[4:]); err != nil {
+		t.Errorf("Expected the batch to succeed, got %v", err)
+	}
+}
+
+func TestParseWorkers(t *testing.T) {
+	if workers, err := parseWorkers("", defaultBatchWorkers); err != nil || workers != 4 {
+		t.Errorf("Expected 4 workers by default, got %d, %v", workers, err)
+	}
+	if _, err := parseWorkers("0", defaultBatchWorkers); err == nil {
+		t.Error("Expected 0 workers to fail")
+	}
+}
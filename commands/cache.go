@@ -0,0 +1,183 @@
+package commands
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/jfrog/jfrog-cli-core/artifactory/spec"
+	rtutils "github.com/jfrog/jfrog-cli-core/artifactory/utils"
+	"github.com/jfrog/jfrog-cli-core/plugins/components"
+	"github.com/jfrog/jfrog-cli-core/utils/config"
+	"github.com/jfrog/jfrog-client-go/utils/errorutils"
+	"github.com/jfrog/jfrog-client-go/utils/log"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	defaultCacheTtl = "10m"
+	cacheDirName    = "release-bundle-generator"
+)
+
+// fileCache stores chart archives and search results on disk, by key, until
+// they're older than ttl. A nil cache stores nothing.
+type fileCache struct {
+	dir string
+	ttl time.Duration
+}
+
+func getCacheFlags() []components.Flag {
+	return []components.Flag{
+		components.BoolFlag{
+			Name:        "no-cache",
+			Description: "Set to true to download charts and search for artifacts again, rather than reading them from the local cache.",
+		},
+		components.StringFlag{
+			Name:         "cache-ttl",
+			Description:  "How long charts and search results are kept in the local cache, as in 30m or 2h.",
+			DefaultValue: defaultCacheTtl,
+		},
+	}
+}
+
+// createCache creates the cache of the cache flags, or nil with --no-cache.
+func createCache(c *components.Context) (*fileCache, error) {
+	if c.GetBoolFlagValue("no-cache") {
+		return nil, nil
+	}
+	ttl := c.GetStringFlagValue("cache-ttl")
+	if ttl == "" {
+		ttl = defaultCacheTtl
+	}
+	duration, err := time.ParseDuration(ttl)
+	if err != nil {
+		return nil, errorutils.CheckError(err)
+	}
+	return newFileCache("", duration)
+}
+
+// newFileCache creates a cache in dir, by default in the cache directory of
+// the user.
+func newFileCache(dir string, ttl time.Duration) (*fileCache, error) {
+	if dir == "" {
+		base, err := os.UserCacheDir()
+		if err != nil {
+			return nil, errorutils.CheckError(err)
+		}
+		dir = filepath.Join(base, cacheDirName)
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, errorutils.CheckError(err)
+	}
+	return &fileCache{dir: dir, ttl: ttl}, nil
+}
+
+// cacheKey returns the key of an entry identified by parts, such as the URL of
+// Artifactory, a path and a checksum.
+func cacheKey(parts ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\n")))
+	return hex.EncodeToString(sum[:])
+}
+
+// get returns the content of an entry, unless it's missing or expired.
+func (fc *fileCache) get(key string) ([]byte, bool) {
+	if fc == nil {
+		return nil, false
+	}
+	path := filepath.Join(fc.dir, key)
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, false
+	}
+	if time.Since(info.ModTime()) > fc.ttl {
+		os.Remove(path)
+		return nil, false
+	}
+	content, err := ioutil.ReadFile(path)
+	return content, err == nil
+}
+
+// put stores an entry. It's written to a temporary file first, so that
+// concurrent commands never read a partial entry. Failing to store an entry
+// only costs a download, so it's logged rather than returned.
+func (fc *fileCache) put(key string, content []byte) {
+	if fc == nil {
+		return
+	}
+	file, err := ioutil.TempFile(fc.dir, key+".tmp")
+	if err != nil {
+		log.Debug("Could not write to the cache: " + err.Error())
+		return
+	}
+	_, err = file.Write(content)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(file.Name(), filepath.Join(fc.dir, key))
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		log.Debug("Could not write to the cache: " + err.Error())
+	}
+}
+
+// remoteChecksum returns the SHA-256 checksum Artifactory has for a file, or an
+// empty string if it has none.
+func remoteChecksum(artDetails *config.ArtifactoryDetails, path string) (string, error) {
+	client, auth, err := createArtifactoryClient(artDetails)
+	if err != nil {
+		return "", err
+	}
+	httpClientDetails := auth.CreateHttpClientDetails()
+	resp, _, err := client.SendHead(urlAppend(artDetails.Url, path), &httpClientDetails)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		return "", err
+	}
+	return resp.Header.Get("X-Checksum-Sha256"), nil
+}
+
+// withCache reads the results of searches from cache, keyed by the URL of
+// Artifactory and the file spec. Searches which find nothing aren't cached, so
+// that artifacts uploaded since are found. Neither are searches which find the
+// manifest of a Docker image: a tag can be pushed again, so the digest of an
+// image, and the signatures and referrers found by that digest, are always
+// resolved from fresh results.
+func withCache(search artifactSearcher, cache *fileCache, rtUrl string) artifactSearcher {
+	if cache == nil {
+		return search
+	}
+	return func(specfiles *spec.SpecFiles) ([]rtutils.SearchResult, error) {
+		key := cacheKey(rtUrl, serializeSpecFiles(specfiles))
+		if content, ok := cache.get(key); ok {
+			results := make([]rtutils.SearchResult, 0)
+			if err := json.Unmarshal(content, &results); err == nil && !findsManifests(results) {
+				return results, nil
+			}
+		}
+		results, err := search(specfiles)
+		if err != nil || len(results) == 0 || findsManifests(results) {
+			return results, err
+		}
+		if content, err := json.Marshal(results); err == nil {
+			cache.put(key, content)
+		}
+		return results, nil
+	}
+}
+
+// findsManifests tells whether results hold the manifest or manifest list of a
+// Docker image.
+func findsManifests(results []rtutils.SearchResult) bool {
+	for _, result := range results {
+		if base := path.Base(result.Path); base == dockerManifestFile || base == dockerManifestListFile {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,86 @@
+package commands
+
+import (
+	"github.com/jfrog/jfrog-cli-core/artifactory/spec"
+	rtutils "github.com/jfrog/jfrog-cli-core/artifactory/utils"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestFileCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	cache, err := newFileCache(dir, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := cacheKey("https://example.com/artifactory/", "helm-local/app-1.0.0.tgz", "abc")
+	if _, ok := cache.get(key); ok {
+		t.Error("Expected an empty cache")
+	}
+	cache.put(key, []byte("chart"))
+	if content, ok := cache.get(key); !ok || string(content) != "chart" {
+		t.Errorf("Expected the cached content, got %q", content)
+	}
+	expired := time.Now().Add(-2 * time.Hour)
+	if err := os.Chtimes(filepath.Join(dir, key), expired, expired); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := cache.get(key); ok {
+		t.Error("Expected the entry to expire")
+	}
+	var disabled *fileCache
+	disabled.put(key, []byte("chart"))
+	if _, ok := disabled.get(key); ok {
+		t.Error("Expected a nil cache to store nothing")
+	}
+}
+
+func TestWithCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	cache, err := newFileCache(dir, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	searches := 0
+	search := withCache(func(specfiles *spec.SpecFiles) ([]rtutils.SearchResult, error) {
+		searches++
+		switch specfiles.Files[0].Pattern {
+		case "docker-local/missing/1.0/":
+			return []rtutils.SearchResult{}, nil
+		case "docker-local/alpine/3.10/":
+			return []rtutils.SearchResult{{Path: "docker-local/alpine/3.10/manifest.json", Props: map[string][]string{manifestDigestProp: {"sha256:" + strconv.Itoa(searches)}}}}, nil
+		}
+		return []rtutils.SearchResult{{Path: "helm-local/app-1.0.0.tgz"}}, nil
+	}, cache, "https://example.com/artifactory/")
+	chart := &spec.SpecFiles{Files: []spec.File{{Pattern: "helm-local/app-1.0.0.tgz"}}}
+	alpine := &spec.SpecFiles{Files: []spec.File{{Pattern: "docker-local/alpine/3.10/"}}}
+	missing := &spec.SpecFiles{Files: []spec.File{{Pattern: "docker-local/missing/1.0/"}}}
+	for i := 0; i < 2; i++ {
+		if results, err := search(chart); err != nil || len(results) != 1 || results[0].Path != "helm-local/app-1.0.0.tgz" {
+			t.Errorf("Unexpected results %v, %v", results, err)
+		}
+		// The digest of an image pushed again is never read from cache.
+		results, err := search(alpine)
+		if err != nil || len(results) != 1 || results[0].Props[manifestDigestProp][0] != "sha256:"+strconv.Itoa(searches) {
+			t.Errorf("Unexpected results %v, %v", results, err)
+		}
+		if _, err := search(missing); err != nil {
+			t.Error(err)
+		}
+	}
+	if searches != 5 {
+		t.Errorf("Expected only the searches finding images or nothing to be repeated, got %d searches", searches)
+	}
+}
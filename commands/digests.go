@@ -0,0 +1,143 @@
+package commands
+
+import (
+	"errors"
+	rtutils "github.com/jfrog/jfrog-cli-core/artifactory/utils"
+	"github.com/jfrog/jfrog-cli-core/utils/config"
+	distributionServicesUtils "github.com/jfrog/jfrog-client-go/distribution/services/utils"
+	"github.com/jfrog/jfrog-client-go/utils/errorutils"
+	"path"
+	"sort"
+	"strings"
+)
+
+const (
+	// manifestDigestProp is set by Artifactory on the manifest of a Docker image.
+	manifestDigestProp = "docker.manifest.digest"
+	// imageDigestProp pins the files of an image in the bundle to its digest.
+	imageDigestProp = "image.digest"
+)
+
+// resolveImageDigests returns the digests of the Docker images among
+// artifacts, by artifact name: the digest Artifactory sets on the manifest of
+// an image found in results, or else the checksum of that manifest, read with
+// read. The manifest of a multi-architecture image is its manifest list.
+// Images without a manifest among results are left out.
+func resolveImageDigests(artifacts []bundleArtifact, results []rtutils.SearchResult, read artifactReader) (map[string]string, error) {
+	byPath := map[string]rtutils.SearchResult{}
+	for _, result := range results {
+		byPath[result.Path] = result
+	}
+	paths := resultPaths(results)
+	digests := map[string]string{}
+	for _, artifact := range artifacts {
+		if artifact.packageType != "docker" {
+			continue
+		}
+		manifests := tagManifests(artifact.matches(paths))
+		if len(manifests) == 0 {
+			continue
+		}
+		manifest := manifests[0]
+		if values := byPath[manifest].Props[manifestDigestProp]; len(values) > 0 && values[0] != "" {
+			digests[artifact.name] = values[0]
+			continue
+		}
+		content, err := readArtifact(read, manifest)
+		if err != nil {
+			return nil, err
+		}
+		digests[artifact.name] = sha256Digest(content)
+	}
+	return digests, nil
+}
+
+// pinImageDigests adds the digest of each image to the properties of its
+// files in the bundle.
+func pinImageDigests(artifacts []bundleArtifact, digests map[string]string) []bundleArtifact {
+	pinned := make([]bundleArtifact, 0)
+	for _, artifact := range artifacts {
+		if digest, ok := digests[artifact.name]; ok && artifact.packageType == "docker" {
+			artifact.addedProps = mergeProps(artifact.addedProps, releaseBundleProp{Key: imageDigestProp, Values: []string{digest}})
+		}
+		pinned = append(pinned, artifact)
+	}
+	return pinned
+}
+
+// verifyImageDigests checks that the manifests of the images in bundle have
+// the digests they were pinned to, which fails if a tag was pushed again
+// between resolving its digest and creating the bundle.
+func verifyImageDigests(artifacts []bundleArtifact, digests map[string]string, bundle *releaseBundleVersion) error {
+	checksums := map[string]string{}
+	paths := make([]string, 0)
+	for _, artifact := range bundle.Artifacts {
+		if base := path.Base(artifact.path()); base == dockerManifestFile || base == dockerManifestListFile {
+			checksums[artifact.path()] = artifact.Checksum
+			paths = append(paths, artifact.path())
+		}
+	}
+	changed := make([]string, 0)
+	for _, artifact := range artifacts {
+		digest, ok := digests[artifact.name]
+		if !ok || artifact.packageType != "docker" {
+			continue
+		}
+		for _, p := range tagManifests(artifact.matches(paths)) {
+			if actual := "sha256:" + strings.TrimPrefix(checksums[p], "sha256:"); actual != digest {
+				changed = append(changed, artifact.name+" changed from "+digest+" to "+actual)
+			}
+		}
+	}
+	if len(changed) > 0 {
+		sort.Strings(changed)
+		return errorutils.CheckError(errors.New("The digest of images changed before the release bundle was created:\n" + strings.Join(changed, "\n")))
+	}
+	return nil
+}
+
+// createVerifiedReleaseBundle creates a release bundle version and checks, as
+// verifyImageDigests does, that its images have the digests they were pinned
+// to. A version to sign is created unsigned and only signed once the check
+// passed, so that a version with images which changed is never signed. A
+// version whose images changed is deleted.
+func createVerifiedReleaseBundle(rtDetails *config.ArtifactoryDetails, params distributionServicesUtils.ReleaseBundleParams, artifacts []bundleArtifact, digests map[string]string) error {
+	sign := params.SignImmediately
+	params.SignImmediately = false
+	if err := createReleaseBundle(rtDetails, params, artifacts, false); err != nil {
+		return err
+	}
+	bundle, err := getReleaseBundleVersion(rtDetails, params.Name, params.Version)
+	if err != nil {
+		return err
+	}
+	if err := verifyImageDigests(artifacts, digests, bundle); err != nil {
+		if deleteErr := deleteReleaseBundle(rtDetails, params.Name, params.Version); deleteErr != nil {
+			return errorutils.CheckError(errors.New(err.Error() + "\nThe unsigned release bundle " + params.Name + "/" + params.Version + " could not be deleted and must be deleted: " + deleteErr.Error()))
+		}
+		return err
+	}
+	if !sign {
+		return nil
+	}
+	return signReleaseBundle(rtDetails, params)
+}
+
+// tagManifests returns the manifest lists among paths, or their image manifests
+// if there are none, leaving out the manifests of the platform images of a
+// manifest list.
+func tagManifests(paths []string) []string {
+	lists, manifests := make([]string, 0), make([]string, 0)
+	for _, p := range paths {
+		switch path.Base(p) {
+		case dockerManifestListFile:
+			lists = append(lists, p)
+		case dockerManifestFile:
+			manifests = append(manifests, p)
+		}
+	}
+	if len(lists) > 0 {
+		return lists
+	}
+	return manifests
+}
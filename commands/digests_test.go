@@ -0,0 +1,149 @@
+package commands
+
+import (
+	"encoding/json"
+	rtutils "github.com/jfrog/jfrog-cli-core/artifactory/utils"
+	"github.com/jfrog/jfrog-cli-core/utils/config"
+	"github.com/jfrog/jfrog-cli-core/utils/coreutils"
+	distributionServicesUtils "github.com/jfrog/jfrog-client-go/distribution/services/utils"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestResolveImageDigests(t *testing.T) {
+	files := imageFiles(t, "docker-local/jfrog/artifactory-jcr/7.4.1", []byte("jcr config"), []byte("jcr layer"))
+	for p, content := range imageFiles(t, "docker-local/alpine/3.10", []byte("alpine config"), []byte("alpine layer")) {
+		files[p] = content
+	}
+	read, paths := writeArtifactoryFiles(t, files)
+	results := make([]rtutils.SearchResult, 0)
+	for _, p := range paths {
+		result := rtutils.SearchResult{Path: p}
+		// Artifactory sets the digest property on the manifest of the image.
+		if p == "docker-local/alpine/3.10/manifest.json" {
+			result.Props = map[string][]string{manifestDigestProp: {"sha256:alpine"}}
+		}
+		results = append(results, result)
+	}
+	artifacts := imageAndChartArtifacts(map[string]string{
+		"docker.bintray.io/jfrog/artifactory-jcr:7.4.1": "docker.bintray.io/jfrog/artifactory-jcr:7.4.1",
+		"alpine:3.10":  "alpine:3.10",
+		"busybox:1.31": "busybox:1.31",
+	}, map[string]string{"helm-local/artifactory-jcr-2.2.0.tgz": "artifactory-jcr-2.2.0.tgz"}, "docker-local")
+
+	digests, err := resolveImageDigests(artifacts, results, read)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jcrDigest := sha256Digest(files["docker-local/jfrog/artifactory-jcr/7.4.1/manifest.json"])
+	expected := map[string]string{"alpine:3.10": "sha256:alpine", "jfrog/artifactory-jcr:7.4.1": jcrDigest}
+	if !reflect.DeepEqual(digests, expected) {
+		t.Errorf("Expected digests %v, got %v", expected, digests)
+	}
+
+	pinned := pinImageDigests(artifacts, digests)
+	for _, artifact := range pinned {
+		props := artifact.addedProps
+		if digest, ok := expected[artifact.name]; ok {
+			if !reflect.DeepEqual(props, []releaseBundleProp{{Key: imageDigestProp, Values: []string{digest}}}) {
+				t.Errorf("Expected %s to be pinned to %s, got %v", artifact.name, digest, props)
+			}
+		} else if len(props) != 0 {
+			t.Errorf("Expected %s not to be pinned, got %v", artifact.name, props)
+		}
+	}
+}
+
+func TestVerifyImageDigests(t *testing.T) {
+	artifacts := imageAndChartArtifacts(map[string]string{"alpine:3.10": "alpine:3.10"}, map[string]string{}, "docker-local")
+	digests := map[string]string{"alpine:3.10": "sha256:0123"}
+	bundle := &releaseBundleVersion{Artifacts: []releaseBundleArtifact{
+		{SourceRepoPath: "docker-local/alpine/3.10/sha256__4567", Checksum: "4567"},
+		{SourceRepoPath: "docker-local/alpine/3.10/manifest.json", Checksum: "0123"},
+	}}
+	if err := verifyImageDigests(artifacts, digests, bundle); err != nil {
+		t.Error(err)
+	}
+
+	bundle.Artifacts[1].Checksum = "89ab"
+	err := verifyImageDigests(artifacts, digests, bundle)
+	if err == nil || !strings.Contains(err.Error(), "alpine:3.10 changed from sha256:0123 to sha256:89ab") {
+		t.Errorf("Expected a changed digest, got %v", err)
+	}
+}
+
+func TestCreateVerifiedReleaseBundle(t *testing.T) {
+	home, err := ioutil.TempDir("", "jfrog-home")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(home)
+	defer os.Setenv(coreutils.HomeDir, os.Getenv(coreutils.HomeDir))
+	os.Setenv(coreutils.HomeDir, home)
+	checksum := "0123"
+	deleteStatus := http.StatusNoContent
+	requests := make([]string, 0)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		switch r.Method + " " + r.URL.Path {
+		case "POST /api/v1/release_bundle":
+			body := &releaseBundleCreateBody{}
+			if err := json.NewDecoder(r.Body).Decode(body); err != nil || body.SignImmediately {
+				t.Errorf("Expected the release bundle to be created unsigned, got %+v, %v", body, err)
+			}
+			w.WriteHeader(http.StatusCreated)
+		case "GET /api/v1/release_bundle/platform/1.5":
+			json.NewEncoder(w).Encode(releaseBundleVersion{Name: "platform", Version: "1.5", Artifacts: []releaseBundleArtifact{
+				{SourceRepoPath: "docker-local/alpine/3.10/manifest.json", Checksum: checksum},
+			}})
+		case "POST /api/v1/release_bundle/platform/1.5/sign":
+			w.Write([]byte("{}"))
+		case "DELETE /api/v1/release_bundle/platform/1.5":
+			w.WriteHeader(deleteStatus)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	rtDetails := &config.ArtifactoryDetails{Url: server.URL + "/artifactory/", DistributionUrl: server.URL + "/", User: "admin", Password: "password"}
+	params := distributionServicesUtils.NewReleaseBundleParams("platform", "1.5")
+	params.SignImmediately = true
+	artifacts := imageAndChartArtifacts(map[string]string{"alpine:3.10": "alpine:3.10"}, map[string]string{}, "docker-local")
+	digests := map[string]string{"alpine:3.10": "sha256:0123"}
+
+	if err := createVerifiedReleaseBundle(rtDetails, params, artifacts, digests); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"POST /api/v1/release_bundle", "GET /api/v1/release_bundle/platform/1.5", "POST /api/v1/release_bundle/platform/1.5/sign"}
+	if !reflect.DeepEqual(requests, expected) {
+		t.Errorf("Expected the release bundle to be signed once verified, got requests %v", requests)
+	}
+
+	// A release bundle with an image which changed is deleted instead of signed.
+	checksum = "89ab"
+	requests = make([]string, 0)
+	err = createVerifiedReleaseBundle(rtDetails, params, artifacts, digests)
+	if err == nil || !strings.Contains(err.Error(), "alpine:3.10 changed from sha256:0123 to sha256:89ab") {
+		t.Errorf("Expected a changed digest, got %v", err)
+	}
+	deleted := append(expected[:2:2], "DELETE /api/v1/release_bundle/platform/1.5")
+	if !reflect.DeepEqual(requests, deleted) {
+		t.Errorf("Expected the release bundle to be deleted, got requests %v", requests)
+	}
+
+	// If it can't be deleted, the error says so.
+	deleteStatus = http.StatusForbidden
+	requests = make([]string, 0)
+	err = createVerifiedReleaseBundle(rtDetails, params, artifacts, digests)
+	if err == nil || !strings.Contains(err.Error(), "alpine:3.10 changed") || !strings.Contains(err.Error(), "platform/1.5 could not be deleted and must be deleted") {
+		t.Errorf("Expected a changed digest and a failed deletion, got %v", err)
+	}
+	if !reflect.DeepEqual(requests, deleted) {
+		t.Errorf("Expected the release bundle to be deleted, got requests %v", requests)
+	}
+}
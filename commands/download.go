@@ -0,0 +1,195 @@
+package commands
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"github.com/jfrog/jfrog-cli-core/plugins/components"
+	"github.com/jfrog/jfrog-cli-core/utils/config"
+	clientutils "github.com/jfrog/jfrog-client-go/utils"
+	"github.com/jfrog/jfrog-client-go/utils/errorutils"
+	"github.com/jfrog/jfrog-client-go/utils/log"
+	"io"
+	"io/ioutil"
+	"k8s.io/helm/pkg/chartutil"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const defaultMaxChartSize = "100"
+
+func getMaxChartSizeFlag() components.StringFlag {
+	return components.StringFlag{
+		Name:         "max-chart-size",
+		Description:  "The size in MB a chart archive downloaded from Artifactory may not exceed, compressed or not.",
+		DefaultValue: defaultMaxChartSize,
+	}
+}
+
+// parseMaxChartSize parses a size in MB, returning it in bytes.
+func parseMaxChartSize(size string) (int64, error) {
+	if size == "" {
+		size = defaultMaxChartSize
+	}
+	mb, err := strconv.ParseInt(size, 10, 64)
+	if err != nil || mb < 1 {
+		return 0, errorutils.CheckError(errors.New("The maximum chart size must be a positive number of MB, got " + size + "."))
+	}
+	return mb << 20, nil
+}
+
+// loadChartArchive downloads a chart archive from Artifactory, or reads it from
+// cache if it holds the archive with the SHA-256 checksum Artifactory has for
+// it, and returns the chart with that checksum. A download is streamed to a
+// temporary file and fails if its checksum isn't the one Artifactory has. An
+// archive larger than maxSize, compressed or not, is refused before the chart
+// is loaded.
+func loadChartArchive(artDetails *config.ArtifactoryDetails, chartPath string, cache *fileCache, maxSize int64) (*chart.Chart, string, error) {
+	chrt, content, err := readChartArchive(artDetails, chartPath, cache, maxSize)
+	if err != nil {
+		return nil, "", err
+	}
+	return chrt, sha256Hex(content), nil
+}
+
+// readChartArchive reads a chart archive as loadChartArchive does, and returns
+// the chart with the content of the archive.
+func readChartArchive(artDetails *config.ArtifactoryDetails, chartPath string, cache *fileCache, maxSize int64) (*chart.Chart, []byte, error) {
+	if maxSize <= 0 {
+		maxSize, _ = parseMaxChartSize(defaultMaxChartSize)
+	}
+	if cache != nil {
+		if checksum, err := remoteChecksum(artDetails, chartPath); err == nil && checksum != "" {
+			if content, ok := cache.get(cacheKey(artDetails.Url, chartPath, checksum)); ok && int64(len(content)) <= maxSize && sha256Hex(content) == checksum {
+				log.Debug("Read " + chartPath + " from the cache.")
+				chrt, err := loadArchiveWithin(bytes.NewReader(content), chartPath, maxSize)
+				return chrt, content, err
+			}
+		}
+	}
+	archive, checksum, err := downloadVerifiedFile(artDetails, chartPath, maxSize)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer os.Remove(archive)
+	content, err := ioutil.ReadFile(archive)
+	if err != nil {
+		return nil, nil, errorutils.CheckError(err)
+	}
+	if cache != nil && checksum != "" {
+		cache.put(cacheKey(artDetails.Url, chartPath, checksum), content)
+	}
+	chrt, err := loadArchiveWithin(bytes.NewReader(content), chartPath, maxSize)
+	return chrt, content, err
+}
+
+// downloadVerifiedFile downloads a file from Artifactory to a temporary file,
+// which the caller removes, and returns it with its SHA-256 checksum. The
+// download fails if the file is larger than maxSize, or if its checksum isn't
+// the one Artifactory has, as returned with the file or by the storage API.
+// Files Artifactory has no SHA-256 checksum for are only warned about.
+func downloadVerifiedFile(artDetails *config.ArtifactoryDetails, downloadPath string, maxSize int64) (string, string, error) {
+	downloadUrl := urlAppend(artDetails.Url, downloadPath)
+	client, auth, err := createArtifactoryClient(artDetails)
+	if err != nil {
+		return "", "", err
+	}
+	httpClientDetails := auth.CreateHttpClientDetails()
+	body, resp, err := client.ReadRemoteFile(downloadUrl, &httpClientDetails)
+	if err != nil {
+		return "", "", err
+	}
+	defer body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", "", errorutils.CheckError(errors.New(resp.Status + " received when attempting to download " + downloadUrl))
+	}
+	expected := resp.Header.Get("X-Checksum-Sha256")
+	if expected == "" {
+		expected, err = storageChecksum(artDetails, downloadPath)
+		if err != nil {
+			return "", "", err
+		}
+	}
+	file, err := ioutil.TempFile("", "chart")
+	if err != nil {
+		return "", "", errorutils.CheckError(err)
+	}
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(file, hash), io.LimitReader(body, maxSize+1))
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && size > maxSize {
+		err = errors.New(downloadPath + " is larger than the maximum size of " + strconv.FormatInt(maxSize>>20, 10) + " MB.")
+	}
+	checksum := hex.EncodeToString(hash.Sum(nil))
+	if err == nil && expected != "" && !strings.EqualFold(checksum, expected) {
+		err = errors.New("The SHA-256 checksum of " + downloadPath + " is " + checksum + ", but Artifactory has " + expected + " for it.")
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		return "", "", errorutils.CheckError(err)
+	}
+	if expected == "" {
+		log.Warn("Artifactory has no SHA-256 checksum for " + downloadPath + ", which could not be verified.")
+	}
+	return file.Name(), checksum, nil
+}
+
+// storageChecksum returns the SHA-256 checksum of a file from the storage API,
+// or an empty string if it has none.
+func storageChecksum(artDetails *config.ArtifactoryDetails, path string) (string, error) {
+	client, auth, err := createArtifactoryClient(artDetails)
+	if err != nil {
+		return "", err
+	}
+	httpClientDetails := auth.CreateHttpClientDetails()
+	storageUrl := clientutils.AddTrailingSlashIfNeeded(artDetails.Url) + "api/storage/" + strings.TrimPrefix(path, "/")
+	resp, body, _, err := client.SendGet(storageUrl, true, &httpClientDetails)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", errorutils.CheckError(errors.New(resp.Status + " received when requesting " + storageUrl))
+	}
+	info := &struct {
+		Checksums struct {
+			Sha256 string `json:"sha256"`
+		} `json:"checksums"`
+	}{}
+	if err := json.Unmarshal(body, info); err != nil {
+		return "", errorutils.CheckError(err)
+	}
+	return info.Checksums.Sha256, nil
+}
+
+// loadArchiveWithin loads a chart archive, read from archive, unless it
+// decompresses to more than maxSize.
+func loadArchiveWithin(archive io.ReadSeeker, chartPath string, maxSize int64) (*chart.Chart, error) {
+	gz, err := gzip.NewReader(archive)
+	if err != nil {
+		return nil, errorutils.CheckError(err)
+	}
+	size, err := io.Copy(ioutil.Discard, io.LimitReader(gz, maxSize+1))
+	if err != nil {
+		return nil, errorutils.CheckError(err)
+	}
+	if size > maxSize {
+		return nil, errorutils.CheckError(errors.New(chartPath + " decompresses to more than the maximum size of " + strconv.FormatInt(maxSize>>20, 10) + " MB."))
+	}
+	if _, err := archive.Seek(0, io.SeekStart); err != nil {
+		return nil, errorutils.CheckError(err)
+	}
+	return chartutil.LoadArchive(archive)
+}
+
+func sha256Hex(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
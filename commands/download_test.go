@@ -0,0 +1,103 @@
+package commands
+
+import (
+	"github.com/jfrog/jfrog-cli-core/utils/config"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// chartStandIn serves the test chart at helm-local/artifactory-jcr-2.2.0.tgz,
+// with checksum as its SHA-256 checksum, and counts its downloads.
+func chartStandIn(t *testing.T, checksum string, inHeader bool, downloads *int) *httptest.Server {
+	content, err := ioutil.ReadFile("testdata/artifactory-jcr-2.2.0.tgz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/artifactory/helm-local/artifactory-jcr-2.2.0.tgz":
+			if inHeader {
+				w.Header().Set("X-Checksum-Sha256", checksum)
+			}
+			if r.Method == http.MethodGet {
+				*downloads++
+				w.Write(content)
+			}
+		case "/artifactory/api/storage/helm-local/artifactory-jcr-2.2.0.tgz":
+			w.Write([]byte(`{"checksums":{"sha256":"` + checksum + `"}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+func TestLoadChartArchive(t *testing.T) {
+	content, err := ioutil.ReadFile("testdata/artifactory-jcr-2.2.0.tgz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checksum := sha256Hex(content)
+	dir, err := ioutil.TempDir("", "cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	cache, err := newFileCache(dir, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	downloads := 0
+	server := chartStandIn(t, checksum, true, &downloads)
+	defer server.Close()
+	rtDetails := &config.ArtifactoryDetails{Url: server.URL + "/artifactory/"}
+	for i := 0; i < 2; i++ {
+		chrt, sum, err := loadChartArchive(rtDetails, "helm-local/artifactory-jcr-2.2.0.tgz", cache, 1<<20)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if chrt.Metadata.Name != "artifactory-jcr" || sum != checksum {
+			t.Errorf("Unexpected chart %s with checksum %s", chrt.Metadata.Name, sum)
+		}
+	}
+	if downloads != 1 {
+		t.Errorf("Expected the chart to be downloaded once, got %d downloads", downloads)
+	}
+	if _, _, err := loadChartArchive(rtDetails, "helm-local/artifactory-jcr-2.2.0.tgz", nil, 1<<10); err == nil || !strings.Contains(err.Error(), "larger than the maximum size") {
+		t.Errorf("Expected the chart to exceed the maximum size, got %v", err)
+	}
+	archive, err := os.Open("testdata/artifactory-jcr-2.2.0.tgz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer archive.Close()
+	if _, err := loadArchiveWithin(archive, "artifactory-jcr-2.2.0.tgz", int64(len(content))); err == nil || !strings.Contains(err.Error(), "decompresses to more than") {
+		t.Errorf("Expected the decompressed chart to exceed the maximum size, got %v", err)
+	}
+}
+
+func TestLoadChartArchiveChecksumMismatch(t *testing.T) {
+	downloads := 0
+	for _, inHeader := range []bool{true, false} {
+		server := chartStandIn(t, strings.Repeat("0", 64), inHeader, &downloads)
+		rtDetails := &config.ArtifactoryDetails{Url: server.URL + "/artifactory/"}
+		_, _, err := loadChartArchive(rtDetails, "helm-local/artifactory-jcr-2.2.0.tgz", nil, 1<<20)
+		if err == nil || !strings.Contains(err.Error(), "but Artifactory has "+strings.Repeat("0", 64)) {
+			t.Errorf("Expected a checksum mismatch, got %v", err)
+		}
+		server.Close()
+	}
+}
+
+func TestParseMaxChartSize(t *testing.T) {
+	if size, err := parseMaxChartSize(""); err != nil || size != 100<<20 {
+		t.Errorf("Expected 100 MB by default, got %d, %v", size, err)
+	}
+	if _, err := parseMaxChartSize("1GB"); err == nil {
+		t.Error("Expected a size with a unit to fail")
+	}
+}
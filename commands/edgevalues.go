@@ -0,0 +1,191 @@
+package commands
+
+import (
+	"fmt"
+	"github.com/ghodss/yaml"
+	"github.com/jfrog/jfrog-client-go/utils/errorutils"
+	"k8s.io/helm/pkg/chartutil"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+	"k8s.io/helm/pkg/renderutil"
+	"sort"
+	"strings"
+)
+
+const edgeValuesSuffix = "-values-edge.yaml"
+
+// imageValue is a value of a chart which sets images: a string, as in
+// image: repo:tag, or a map with a repository and an optional registry and
+// tag. Its path is the keys leading to it from the values of the chart.
+type imageValue struct {
+	path   []string
+	values map[string]interface{}
+	images []string
+}
+
+// traceImageValues finds the values of chrt which set the images it uses. A
+// candidate value, which looks like an image the chart uses, is replaced by a
+// marker, and sets the images which are gone from the files rendered with it.
+func traceImageValues(chrt *chart.Chart) ([]imageValue, error) {
+	base, err := renderFileImages(chrt, "{}")
+	if err != nil {
+		return nil, err
+	}
+	names, repositories := map[string]bool{}, map[string]bool{}
+	for _, images := range base {
+		for ref := range images {
+			image := parseImageReference(ref)
+			names[image.name()] = true
+			repositories[image.repository] = true
+		}
+	}
+	values, err := chartutil.CoalesceValues(chrt, &chart.Config{Raw: "{}"})
+	if err != nil {
+		return nil, errorutils.CheckError(err)
+	}
+	traced := make([]imageValue, 0)
+	for i, candidate := range findImageValues(values, nil, names, repositories) {
+		key := candidate.path
+		if candidate.values != nil {
+			key = appendKey(key, "repository")
+		}
+		override := map[string]interface{}{}
+		setValue(override, key, fmt.Sprintf("image-value-marker-%d", i))
+		raw, err := yaml.Marshal(override)
+		if err != nil {
+			return nil, errorutils.CheckError(err)
+		}
+		marked, err := renderFileImages(chrt, string(raw))
+		if err != nil {
+			return nil, err
+		}
+		gone := map[string]bool{}
+		for file, images := range base {
+			for ref := range images {
+				if _, ok := marked[file][ref]; !ok {
+					gone[ref] = true
+				}
+			}
+		}
+		if len(gone) > 0 {
+			candidate.images = sortedSet(gone)
+			traced = append(traced, candidate)
+		}
+	}
+	return traced, nil
+}
+
+// findImageValues returns the string values which are images among names, and
+// the maps whose repository is among repositories, without looking into lists,
+// which can't be overridden in part.
+func findImageValues(values map[string]interface{}, path []string, names, repositories map[string]bool) []imageValue {
+	found := make([]imageValue, 0)
+	keys := make([]string, 0)
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	if repository, ok := values["repository"].(string); ok && repositories[parseImageReference(repository).repository] {
+		found = append(found, imageValue{path: path, values: values})
+	}
+	for _, key := range keys {
+		keyPath := appendKey(path, key)
+		switch v := values[key].(type) {
+		case string:
+			if key != "repository" && names[parseImageReference(v).name()] {
+				found = append(found, imageValue{path: keyPath})
+			}
+		case map[string]interface{}:
+			found = append(found, findImageValues(v, keyPath, names, repositories)...)
+		}
+	}
+	return found
+}
+
+func renderFileImages(chrt *chart.Chart, raw string) (map[string]map[string]string, error) {
+	files, err := renderutil.Render(chrt, &chart.Config{Raw: raw}, renderutil.Options{})
+	if err != nil {
+		return nil, errorutils.CheckError(err)
+	}
+	images := map[string]map[string]string{}
+	for name, content := range files {
+		images[name] = extractImages(map[string]string{name: content})
+	}
+	return images, nil
+}
+
+func appendKey(path []string, key string) []string {
+	return append(append([]string{}, path...), key)
+}
+
+func setValue(values map[string]interface{}, path []string, value interface{}) {
+	for _, key := range path[:len(path)-1] {
+		next, ok := values[key].(map[string]interface{})
+		if !ok {
+			next = map[string]interface{}{}
+			values[key] = next
+		}
+		values = next
+	}
+	values[path[len(path)-1]] = value
+}
+
+// createEdgeValues creates a values file setting the images chrt uses to their
+// location under prefix, a registry optionally followed by a repository path,
+// through the values they come from. It returns the images the values file
+// doesn't relocate as well.
+func createEdgeValues(chrt *chart.Chart, prefix string) ([]byte, []string, error) {
+	traced, err := traceImageValues(chrt)
+	if err != nil {
+		return nil, nil, err
+	}
+	images, err := renderImages(chrt)
+	if err != nil {
+		return nil, nil, err
+	}
+	r := newImageRelocation(images, prefix)
+	registry, repositoryPath := r.prefix, ""
+	if i := strings.Index(r.prefix, "/"); i >= 0 {
+		registry, repositoryPath = r.prefix[:i], r.prefix[i+1:]+"/"
+	}
+	overrides := map[string]interface{}{}
+	for _, value := range traced {
+		ref := value.images[0]
+		target, ok := r.targets[ref]
+		if !ok {
+			continue
+		}
+		if value.values == nil {
+			setValue(overrides, value.path, target)
+			continue
+		}
+		image := parseImageReference(ref)
+		if _, ok := value.values["registry"]; ok {
+			setValue(overrides, appendKey(value.path, "registry"), registry)
+			setValue(overrides, appendKey(value.path, "repository"), repositoryPath+image.repository)
+		} else {
+			setValue(overrides, appendKey(value.path, "repository"), r.prefix+"/"+image.repository)
+		}
+		if _, ok := value.values["digest"]; ok && image.digest != "" {
+			setValue(overrides, appendKey(value.path, "digest"), image.digest)
+		} else if _, ok := value.values["tag"]; ok && image.digest == "" {
+			setValue(overrides, appendKey(value.path, "tag"), image.tag)
+		}
+	}
+	content, err := yaml.Marshal(overrides)
+	if err != nil {
+		return nil, nil, errorutils.CheckError(err)
+	}
+	relocated, err := renderImagesWithValues(chrt, string(content))
+	if err != nil {
+		return nil, nil, err
+	}
+	remaining := make([]string, 0)
+	for ref := range relocated {
+		if !r.relocated(ref) {
+			remaining = append(remaining, ref)
+		}
+	}
+	sort.Strings(remaining)
+	header := "# Image overrides for " + chrt.Metadata.Name + " " + chrt.Metadata.Version + ", pulling its images from " + r.prefix + ".\n"
+	return append([]byte(header), content...), remaining, nil
+}
@@ -0,0 +1,108 @@
+package commands
+
+import (
+	"k8s.io/helm/pkg/chartutil"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+	"reflect"
+	"testing"
+)
+
+func TestCreateEdgeValues(t *testing.T) {
+	chrt, err := chartutil.Load("testdata/artifactory-jcr-2.2.0.tgz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, remaining, err := createEdgeValues(chrt, "edge.example.com/docker-edge")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `# Image overrides for artifactory-jcr 2.2.0, pulling its images from edge.example.com/docker-edge.
+artifactory:
+  artifactory:
+    image:
+      repository: edge.example.com/docker-edge/jfrog/artifactory-jcr
+  initContainerImage: edge.example.com/docker-edge/alpine:3.10
+  nginx:
+    image:
+      repository: edge.example.com/docker-edge/jfrog/nginx-artifactory-pro
+  postgresql:
+    image:
+      registry: edge.example.com
+      repository: docker-edge/bitnami/postgresql
+      tag: 9.6.17-debian-10-r21
+`
+	if string(content) != expected {
+		t.Errorf("Expected values:\n%s\ngot:\n%s", expected, content)
+	}
+	if len(remaining) != 0 {
+		t.Errorf("Expected all images to be relocated, got %v", remaining)
+	}
+}
+
+func TestTraceImageValues(t *testing.T) {
+	chrt := &chart.Chart{
+		Metadata: &chart.Metadata{Name: "app", Version: "1.0.0"},
+		Values: &chart.Config{Raw: `
+image:
+  registry: quay.io
+  repository: coreos/etcd
+  tag: v3.4.13
+backup:
+  enabled: false
+  image: coreos/etcd:v3.4.13
+sidecar: busybox:1.31
+`},
+		Templates: []*chart.Template{{
+			Name: "templates/deployment.yaml",
+			Data: []byte(`containers:
+  - name: etcd
+    image: {{ .Values.image.registry }}/{{ .Values.image.repository }}:{{ .Values.image.tag }}
+  - name: sidecar
+    image: {{ .Values.sidecar }}
+  - name: logs
+    image: {{ .Values.sidecar }}
+  - name: pause
+    image: k8s.gcr.io/pause:3.2
+{{- if .Values.backup.enabled }}
+  - name: backup
+    image: {{ .Values.backup.image }}
+{{- end }}
+`),
+		}},
+	}
+	traced, err := traceImageValues(chrt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The backup image is disabled, and the pause image isn't set by values.
+	expected := []imageValue{
+		{path: []string{"image"}, images: []string{"quay.io/coreos/etcd:v3.4.13"}},
+		{path: []string{"sidecar"}, images: []string{"busybox:1.31"}},
+	}
+	if len(traced) != len(expected) {
+		t.Fatalf("Expected %d image values, got %+v", len(expected), traced)
+	}
+	for i := range expected {
+		if !reflect.DeepEqual(traced[i].path, expected[i].path) || !reflect.DeepEqual(traced[i].images, expected[i].images) {
+			t.Errorf("Expected image value %+v, got %+v", expected[i], traced[i])
+		}
+	}
+
+	content, remaining, err := createEdgeValues(chrt, "edge.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedValues := `# Image overrides for app 1.0.0, pulling its images from edge.example.com.
+image:
+  registry: edge.example.com
+  repository: coreos/etcd
+  tag: v3.4.13
+sidecar: edge.example.com/busybox:1.31
+`
+	if string(content) != expectedValues {
+		t.Errorf("Expected values:\n%s\ngot:\n%s", expectedValues, content)
+	}
+	if !reflect.DeepEqual(remaining, []string{"k8s.gcr.io/pause:3.2"}) {
+		t.Errorf("Expected the pause image not to be relocated, got %v", remaining)
+	}
+}
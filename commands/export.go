@@ -0,0 +1,553 @@
+package commands
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/ghodss/yaml"
+	rtcommands "github.com/jfrog/jfrog-cli-core/artifactory/commands"
+	"github.com/jfrog/jfrog-cli-core/artifactory/spec"
+	rtutils "github.com/jfrog/jfrog-cli-core/artifactory/utils"
+	"github.com/jfrog/jfrog-cli-core/plugins/components"
+	"github.com/jfrog/jfrog-cli-core/utils/config"
+	distributionServicesUtils "github.com/jfrog/jfrog-client-go/distribution/services/utils"
+	"github.com/jfrog/jfrog-client-go/utils/errorutils"
+	"io"
+	"io/ioutil"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	ociLayoutDir         = "oci"
+	helmRepoDir          = "helm"
+	checksumsFile        = "SHA256SUMS"
+	bundleMetadataFile   = "bundle.json"
+	ociImageLayout       = `{"imageLayoutVersion":"1.0.0"}`
+	ociIndexMediaType    = "application/vnd.oci.image.index.v1+json"
+	ociRefNameAnnotation = "org.opencontainers.image.ref.name"
+	dockerManifestFile   = "manifest.json"
+)
+
+type ExportCommand struct {
+	rtDetails       *config.ArtifactoryDetails
+	sourceChartPath string
+	dockerRepo      string
+	bundle          string
+	output          string
+	cache           *fileCache
+	maxChartSize    int64
+}
+
+// bundleMetadata is the release bundle version an archive is exported for, to
+// recreate it where the archive is imported.
+type bundleMetadata struct {
+	Name         string                                  `json:"name"`
+	Version      string                                  `json:"version"`
+	Description  string                                  `json:"description,omitempty"`
+	ReleaseNotes *distributionServicesUtils.ReleaseNotes `json:"release_notes,omitempty"`
+}
+
+// artifactReader opens a file in Artifactory, given as repo/path.
+type artifactReader func(path string) (io.ReadCloser, error)
+
+// chartArchiveReader reads a chart archive in Artifactory, given as repo/path,
+// and returns the chart with the content of the archive.
+type chartArchiveReader func(path string) (*chart.Chart, []byte, error)
+
+// ociDescriptor describes a blob of an OCI image layout, or of a Docker image
+// manifest.
+type ociDescriptor struct {
+	MediaType   string            `json:"mediaType,omitempty"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+type ociIndex struct {
+	SchemaVersion int             `json:"schemaVersion"`
+	MediaType     string          `json:"mediaType"`
+	Manifests     []ociDescriptor `json:"manifests"`
+}
+
+// imageManifest is the manifest.json Artifactory stores in the folder of a
+// Docker image, next to its config and layers.
+type imageManifest struct {
+	SchemaVersion int             `json:"schemaVersion"`
+	MediaType     string          `json:"mediaType"`
+	Config        ociDescriptor   `json:"config"`
+	Layers        []ociDescriptor `json:"layers"`
+}
+
+type helmIndex struct {
+	APIVersion string                      `json:"apiVersion"`
+	Entries    map[string][]helmIndexEntry `json:"entries"`
+	Generated  string                      `json:"generated"`
+}
+
+type helmIndexEntry struct {
+	*chart.Metadata
+	URLs    []string `json:"urls"`
+	Created string   `json:"created"`
+	Digest  string   `json:"digest"`
+}
+
+// airGapArchive writes the files of an export to a tarball, keeping the
+// SHA-256 checksum of each of them.
+type airGapArchive struct {
+	tw        *tar.Writer
+	modTime   time.Time
+	checksums map[string]string
+}
+
+func GetExportCommand() components.Command {
+	return components.Command{
+		Name:        "export",
+		Description: "Export the Helm charts and Docker images a Helm chart requires to an archive, to bring them to an air-gapped environment.",
+		Aliases:     []string{"ex"},
+		Arguments:   []components.Argument{},
+		Flags:       getExportFlags(),
+		EnvVars:     []components.EnvVar{},
+		Action: func(c *components.Context) error {
+			return exportCmd(c)
+		},
+	}
+}
+
+func getExportFlags() []components.Flag {
+	flags := append(getArtifactoryFlags(),
+		components.StringFlag{
+			Name:        "chart-path",
+			Description: "Path to a Helm chart in Artifactory, whose artifacts should be exported.",
+			Mandatory:   true,
+		},
+		components.StringFlag{
+			Name:        "docker-repo",
+			Description: "A Docker repository containing all the Docker images the Helm chart requires.",
+			Mandatory:   true,
+		},
+		components.StringFlag{
+			Name:        "bundle",
+			Description: "An existing release bundle, as in name/version, whose name, version, description and release notes should be stored in the archive, to recreate the bundle when importing it.",
+		},
+		components.StringFlag{
+			Name:        "output",
+			Description: "File to write the archive to. It's compressed if its name ends with .gz or .tgz.",
+			Mandatory:   true,
+		})
+	return append(append(flags, getMaxChartSizeFlag()), getCacheFlags()...)
+}
+
+func exportCmd(c *components.Context) error {
+	chartpath := c.GetStringFlagValue("chart-path")
+	dockerrepo := c.GetStringFlagValue("docker-repo")
+	output := c.GetStringFlagValue("output")
+	if !(len(c.Arguments) == 0 && chartpath != "" && dockerrepo != "" && output != "") {
+		return errors.New("Wrong number of arguments.")
+	}
+	rtDetails, err := createArtifactoryDetailsByFlags(c)
+	if err != nil {
+		return err
+	}
+	cache, err := createCache(c)
+	if err != nil {
+		return err
+	}
+	maxChartSize, err := parseMaxChartSize(c.GetStringFlagValue("max-chart-size"))
+	if err != nil {
+		return err
+	}
+	exportCmd := NewExportCommand()
+	exportCmd.SetRtDetails(rtDetails).SetSourceChartPath(chartpath).SetDockerRepo(dockerrepo).SetBundle(c.GetStringFlagValue("bundle")).SetOutput(output).SetCache(cache).SetMaxChartSize(maxChartSize)
+	return rtcommands.Exec(exportCmd)
+}
+
+func NewExportCommand() *ExportCommand {
+	return &ExportCommand{}
+}
+
+func (ec *ExportCommand) SetRtDetails(rtDetails *config.ArtifactoryDetails) *ExportCommand {
+	ec.rtDetails = rtDetails
+	return ec
+}
+
+func (ec *ExportCommand) SetSourceChartPath(sourceChartPath string) *ExportCommand {
+	ec.sourceChartPath = sourceChartPath
+	return ec
+}
+
+func (ec *ExportCommand) SetDockerRepo(dockerRepo string) *ExportCommand {
+	ec.dockerRepo = dockerRepo
+	return ec
+}
+
+func (ec *ExportCommand) SetBundle(bundle string) *ExportCommand {
+	ec.bundle = bundle
+	return ec
+}
+
+func (ec *ExportCommand) SetOutput(output string) *ExportCommand {
+	ec.output = output
+	return ec
+}
+
+func (ec *ExportCommand) SetCache(cache *fileCache) *ExportCommand {
+	ec.cache = cache
+	return ec
+}
+
+func (ec *ExportCommand) SetMaxChartSize(maxChartSize int64) *ExportCommand {
+	ec.maxChartSize = maxChartSize
+	return ec
+}
+
+func (ec *ExportCommand) Run() error {
+	var metadata *bundleMetadata
+	if ec.bundle != "" {
+		name, version, err := parseBundleNameAndVersion(ec.bundle)
+		if err != nil {
+			return err
+		}
+		original, err := getReleaseBundleVersion(ec.rtDetails, name, version)
+		if err != nil {
+			return err
+		}
+		metadata = &bundleMetadata{Name: original.Name, Version: original.Version, Description: original.Description, ReleaseNotes: original.ReleaseNotes}
+	}
+	chrt, _, err := loadChartArchive(ec.rtDetails, ec.sourceChartPath, ec.cache, ec.maxChartSize)
+	if err != nil {
+		return err
+	}
+	_, artifacts, err := createFilespec(chrt, extractRepo(ec.sourceChartPath), ec.dockerRepo)
+	if err != nil {
+		return err
+	}
+	search := withCache(withRetries(func(specfiles *spec.SpecFiles) ([]rtutils.SearchResult, error) {
+		return searchExisting(ec.rtDetails, specfiles)
+	}), ec.cache, ec.rtDetails.Url)
+	workers, _ := strconv.Atoi(defaultResolveWorkers)
+	results, err := resolveArtifacts(artifacts, search, workers)
+	if err != nil {
+		return err
+	}
+	read := func(path string) (io.ReadCloser, error) {
+		return readFileFromArtifactory(ec.rtDetails, path)
+	}
+	artifacts, platforms, err := expandManifestLists(artifacts, results, read, nil)
+	if err != nil {
+		return err
+	}
+	if len(platforms) > 0 {
+		results, err = resolveArtifacts(artifacts, search, workers)
+		if err != nil {
+			return err
+		}
+	}
+	actual := resultPaths(results)
+	file, err := os.Create(ec.output)
+	if err != nil {
+		return errorutils.CheckError(err)
+	}
+	var out io.WriteCloser = file
+	if strings.HasSuffix(ec.output, ".gz") || strings.HasSuffix(ec.output, ".tgz") {
+		out = gzip.NewWriter(file)
+	}
+	// Chart archives are checked as the source chart is.
+	readChart := func(chartPath string) (*chart.Chart, []byte, error) {
+		return readChartArchive(ec.rtDetails, chartPath, ec.cache, ec.maxChartSize)
+	}
+	exported, missing, err := exportArtifacts(out, artifacts, actual, read, readChart, metadata)
+	if err == nil && out != io.WriteCloser(file) {
+		err = errorutils.CheckError(out.Close())
+	}
+	if closeErr := file.Close(); err == nil {
+		err = errorutils.CheckError(closeErr)
+	}
+	if err != nil {
+		os.Remove(ec.output)
+		return err
+	}
+	printReport(os.Stdout, "Exported:", exported, missing)
+	return nil
+}
+
+func (ec *ExportCommand) RtDetails() (*config.ArtifactoryDetails, error) {
+	return ec.rtDetails, nil
+}
+
+func (ec *ExportCommand) CommandName() string {
+	return "rt_export"
+}
+
+// exportArtifacts writes the Docker images and Helm charts of artifacts found
+// at paths to w, as a tarball with the images in an OCI image layout under
+// oci/, the charts in a Helm repository under helm/, and the SHA-256 checksums
+// of all files in SHA256SUMS. The release bundle metadata, if any, is written to
+// bundle.json. Chart archives are read with readChart, and other files with
+// read. It returns the names of the artifacts it exported and of those it
+// couldn't find.
+func exportArtifacts(w io.Writer, artifacts []bundleArtifact, paths []string, read artifactReader, readChart chartArchiveReader, metadata *bundleMetadata) ([]string, []string, error) {
+	archive := newAirGapArchive(w)
+	index := ociIndex{SchemaVersion: 2, MediaType: ociIndexMediaType, Manifests: make([]ociDescriptor, 0)}
+	charts := helmIndex{APIVersion: "v1", Entries: map[string][]helmIndexEntry{}, Generated: archive.modTime.Format(time.RFC3339Nano)}
+	exported := make([]string, 0)
+	missing := make([]string, 0)
+	for _, artifact := range artifacts {
+		matches := artifact.matches(paths)
+		found := false
+		switch artifact.packageType {
+		case "docker":
+			descriptor, err := exportImage(archive, read, artifact, matches)
+			if err != nil {
+				return nil, nil, err
+			}
+			if descriptor != nil {
+				index.Manifests = append(index.Manifests, *descriptor)
+				found = true
+			}
+		case "helm":
+			if len(matches) > 0 {
+				entry, err := exportChart(archive, readChart, matches[0])
+				if err != nil {
+					return nil, nil, err
+				}
+				charts.Entries[entry.Name] = append(charts.Entries[entry.Name], *entry)
+				found = true
+			}
+		}
+		if found {
+			exported = append(exported, artifact.name)
+		} else {
+			missing = append(missing, artifact.name)
+		}
+	}
+	content, err := yaml.Marshal(charts)
+	if err != nil {
+		return nil, nil, errorutils.CheckError(err)
+	}
+	if err := archive.add(helmRepoDir+"/index.yaml", content); err != nil {
+		return nil, nil, err
+	}
+	content, err = json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return nil, nil, errorutils.CheckError(err)
+	}
+	if err := archive.add(ociLayoutDir+"/index.json", content); err != nil {
+		return nil, nil, err
+	}
+	if err := archive.add(ociLayoutDir+"/oci-layout", []byte(ociImageLayout)); err != nil {
+		return nil, nil, err
+	}
+	if metadata != nil {
+		content, err = json.MarshalIndent(metadata, "", "  ")
+		if err != nil {
+			return nil, nil, errorutils.CheckError(err)
+		}
+		if err := archive.add(bundleMetadataFile, content); err != nil {
+			return nil, nil, err
+		}
+	}
+	return exported, missing, archive.close()
+}
+
+// exportImage writes the image of artifact to the OCI image layout, from the
+// folder of its manifest among paths. The manifest list of a multi-architecture
+// image is written as an image index, with the image of each platform. It
+// returns nil if there's no manifest.
+func exportImage(archive *airGapArchive, read artifactReader, artifact bundleArtifact, paths []string) (*ociDescriptor, error) {
+	manifests := tagManifests(paths)
+	if len(manifests) == 0 {
+		return nil, nil
+	}
+	var descriptor *ociDescriptor
+	var err error
+	if path.Base(manifests[0]) == dockerManifestListFile {
+		descriptor, err = exportManifestList(archive, read, manifests[0], paths)
+	} else {
+		descriptor, err = exportManifest(archive, read, manifests[0])
+	}
+	if err != nil {
+		return nil, err
+	}
+	ref := artifact.source
+	if ref == "" {
+		ref = artifact.name
+	}
+	descriptor.Annotations = map[string]string{ociRefNameAnnotation: ref}
+	return descriptor, nil
+}
+
+// exportManifestList writes the manifest list at listPath, and the image of
+// each platform it lists, which must be among paths.
+func exportManifestList(archive *airGapArchive, read artifactReader, listPath string, paths []string) (*ociDescriptor, error) {
+	content, err := readArtifact(read, listPath)
+	if err != nil {
+		return nil, err
+	}
+	list := manifestList{}
+	if err := json.Unmarshal(content, &list); err != nil {
+		return nil, errorutils.CheckError(errors.New("Failed to parse the manifest list " + listPath + ": " + err.Error()))
+	}
+	// The platform images are stored next to the tag folder.
+	imageFolder := path.Dir(path.Dir(listPath))
+	for _, platform := range list.Manifests {
+		manifestPath := imageFolder + "/" + strings.ReplaceAll(platform.Digest, ":", "__") + "/" + dockerManifestFile
+		if !containsString(paths, manifestPath) {
+			return nil, errorutils.CheckError(errors.New("The manifest list " + listPath + " references " + platform.Digest + ", which was not found at " + manifestPath + "."))
+		}
+		descriptor, err := exportManifest(archive, read, manifestPath)
+		if err != nil {
+			return nil, err
+		}
+		if descriptor.Digest != platform.Digest {
+			return nil, errorutils.CheckError(fmt.Errorf("%s doesn't match its digest %s.", manifestPath, platform.Digest))
+		}
+	}
+	digest := sha256Digest(content)
+	if err := archive.add(ociBlobPath(digest), content); err != nil {
+		return nil, err
+	}
+	return &ociDescriptor{MediaType: list.MediaType, Digest: digest, Size: int64(len(content))}, nil
+}
+
+// exportManifest writes the image manifest at manifestPath, with the config
+// and layers stored next to it.
+func exportManifest(archive *airGapArchive, read artifactReader, manifestPath string) (*ociDescriptor, error) {
+	content, err := readArtifact(read, manifestPath)
+	if err != nil {
+		return nil, err
+	}
+	manifest := imageManifest{}
+	if err := json.Unmarshal(content, &manifest); err != nil {
+		return nil, errorutils.CheckError(err)
+	}
+	if manifest.SchemaVersion != 2 {
+		return nil, errorutils.CheckError(fmt.Errorf("Manifest %s has schema version %d, only version 2 can be exported.", manifestPath, manifest.SchemaVersion))
+	}
+	folder := path.Dir(manifestPath)
+	for _, blob := range append([]ociDescriptor{manifest.Config}, manifest.Layers...) {
+		if err := exportBlob(archive, read, folder+"/"+strings.ReplaceAll(blob.Digest, ":", "__"), blob); err != nil {
+			return nil, err
+		}
+	}
+	digest := sha256Digest(content)
+	if err := archive.add(ociBlobPath(digest), content); err != nil {
+		return nil, err
+	}
+	return &ociDescriptor{MediaType: manifest.MediaType, Digest: digest, Size: int64(len(content))}, nil
+}
+
+func exportBlob(archive *airGapArchive, read artifactReader, blobPath string, blob ociDescriptor) error {
+	body, err := read(blobPath)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+	return archive.addBlob(ociBlobPath(blob.Digest), blob, body)
+}
+
+// exportChart writes the chart archive at chartPath to the Helm repository,
+// and returns its entry in the repository index.
+func exportChart(archive *airGapArchive, readChart chartArchiveReader, chartPath string) (*helmIndexEntry, error) {
+	chrt, content, err := readChart(chartPath)
+	if err != nil {
+		return nil, err
+	}
+	name := path.Base(chartPath)
+	if err := archive.add(helmRepoDir+"/"+name, content); err != nil {
+		return nil, err
+	}
+	return &helmIndexEntry{
+		Metadata: chrt.Metadata,
+		URLs:     []string{name},
+		Created:  archive.modTime.Format(time.RFC3339Nano),
+		Digest:   strings.TrimPrefix(sha256Digest(content), "sha256:"),
+	}, nil
+}
+
+func readArtifact(read artifactReader, path string) ([]byte, error) {
+	body, err := read(path)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	content, err := ioutil.ReadAll(body)
+	return content, errorutils.CheckError(err)
+}
+
+func newAirGapArchive(w io.Writer) *airGapArchive {
+	return &airGapArchive{tw: tar.NewWriter(w), modTime: time.Now().UTC(), checksums: map[string]string{}}
+}
+
+// add writes a file to the archive. Files already in the archive, such as
+// layers shared by several images, are written once.
+func (aa *airGapArchive) add(name string, content []byte) error {
+	return aa.write(name, int64(len(content)), bytes.NewReader(content), "")
+}
+
+// addBlob writes a blob streamed from r to the archive, failing if it doesn't
+// match the size and digest of its descriptor.
+func (aa *airGapArchive) addBlob(name string, blob ociDescriptor, r io.Reader) error {
+	return aa.write(name, blob.Size, r, blob.Digest)
+}
+
+func (aa *airGapArchive) write(name string, size int64, r io.Reader, digest string) error {
+	if _, ok := aa.checksums[name]; ok {
+		return nil
+	}
+	header := &tar.Header{Name: name, Mode: 0644, Size: size, ModTime: aa.modTime, Typeflag: tar.TypeReg}
+	if err := aa.tw.WriteHeader(header); err != nil {
+		return errorutils.CheckError(err)
+	}
+	hash := sha256.New()
+	written, err := io.Copy(aa.tw, io.TeeReader(r, hash))
+	if err == tar.ErrWriteTooLong || (err == nil && written != size) {
+		return errorutils.CheckError(fmt.Errorf("%s doesn't have the expected size of %d bytes.", name, size))
+	}
+	if err != nil {
+		return errorutils.CheckError(err)
+	}
+	checksum := hex.EncodeToString(hash.Sum(nil))
+	if digest != "" && digest != "sha256:"+checksum {
+		return errorutils.CheckError(fmt.Errorf("%s doesn't match its digest %s.", name, digest))
+	}
+	aa.checksums[name] = checksum
+	return nil
+}
+
+// close writes the checksums of all files, in the format of sha256sum, and
+// closes the archive.
+func (aa *airGapArchive) close() error {
+	names := make([]string, 0)
+	for name := range aa.checksums {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var sums bytes.Buffer
+	for _, name := range names {
+		sums.WriteString(aa.checksums[name] + "  " + name + "\n")
+	}
+	if err := aa.add(checksumsFile, sums.Bytes()); err != nil {
+		return err
+	}
+	return errorutils.CheckError(aa.tw.Close())
+}
+
+func ociBlobPath(digest string) string {
+	return ociLayoutDir + "/blobs/" + strings.Replace(digest, ":", "/", 1)
+}
+
+func sha256Digest(content []byte) string {
+	sum := sha256.Sum256(content)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
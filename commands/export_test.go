@@ -0,0 +1,301 @@
+package commands
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/json"
+	"github.com/ghodss/yaml"
+	rtutils "github.com/jfrog/jfrog-cli-core/artifactory/utils"
+	"github.com/jfrog/jfrog-cli-core/utils/config"
+	"io"
+	"io/ioutil"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeArtifactoryFiles writes files, keyed by their repo/path, to a folder
+// standing in for Artifactory, and returns a reader of that folder and the
+// paths of all files.
+func writeArtifactoryFiles(t *testing.T, files map[string][]byte) (artifactReader, []string) {
+	dir, err := ioutil.TempDir("", "export")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	paths := make([]string, 0)
+	for p, content := range files {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(dir, p)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, p), content, 0644); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, p)
+	}
+	read := func(p string) (io.ReadCloser, error) {
+		return os.Open(filepath.Join(dir, p))
+	}
+	return read, paths
+}
+
+// chartReader reads the chart archives of a folder standing in for Artifactory
+// with read.
+func chartReader(read artifactReader) chartArchiveReader {
+	return func(p string) (*chart.Chart, []byte, error) {
+		content, err := readArtifact(read, p)
+		if err != nil {
+			return nil, nil, err
+		}
+		chrt, err := loadArchiveWithin(bytes.NewReader(content), p, 100<<20)
+		return chrt, content, err
+	}
+}
+
+func imageFiles(t *testing.T, folder string, config []byte, layers ...[]byte) map[string][]byte {
+	manifest := imageManifest{
+		SchemaVersion: 2,
+		MediaType:     "application/vnd.docker.distribution.manifest.v2+json",
+		Config:        ociDescriptor{MediaType: "application/vnd.docker.container.image.v1+json", Digest: sha256Digest(config), Size: int64(len(config))},
+	}
+	files := map[string][]byte{folder + "/sha256__" + strings.TrimPrefix(sha256Digest(config), "sha256:"): config}
+	for _, layer := range layers {
+		manifest.Layers = append(manifest.Layers, ociDescriptor{MediaType: "application/vnd.docker.image.rootfs.diff.tar.gzip", Digest: sha256Digest(layer), Size: int64(len(layer))})
+		files[folder+"/sha256__"+strings.TrimPrefix(sha256Digest(layer), "sha256:")] = layer
+	}
+	content, err := json.Marshal(manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files[folder+"/"+dockerManifestFile] = content
+	return files
+}
+
+func readTarball(t *testing.T, content []byte) map[string][]byte {
+	files := map[string][]byte{}
+	tr := tar.NewReader(bytes.NewReader(content))
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return files
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := files[header.Name]; ok {
+			t.Error("Duplicate file in archive: " + header.Name)
+		}
+		files[header.Name], err = ioutil.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestExportArtifacts(t *testing.T) {
+	chartArchive, err := ioutil.ReadFile("testdata/artifactory-jcr-2.2.0.tgz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	shared := []byte("shared layer")
+	files := map[string][]byte{"helm-local/artifactory-jcr-2.2.0.tgz": chartArchive}
+	for p, content := range imageFiles(t, "docker-local/jfrog/artifactory-jcr/7.4.1", []byte("jcr config"), shared, []byte("jcr layer")) {
+		files[p] = content
+	}
+	for p, content := range imageFiles(t, "docker-local/library/alpine/3.10", []byte("alpine config"), shared) {
+		files[p] = content
+	}
+	read, paths := writeArtifactoryFiles(t, files)
+
+	artifacts := imageAndChartArtifacts(map[string]string{
+		"docker.bintray.io/jfrog/artifactory-jcr:7.4.1": "docker.bintray.io/jfrog/artifactory-jcr:7.4.1",
+		"alpine:3.10":         "alpine:3.10",
+		"bitnami/redis:6.0.8": "bitnami/redis:6.0.8",
+	}, map[string]string{
+		"helm-local/artifactory-jcr-2.2.0.tgz": "artifactory-jcr-2.2.0.tgz",
+		"helm-local/postgresql-8.7.3.tgz":      "postgresql-8.7.3.tgz",
+	}, "docker-local")
+	var out bytes.Buffer
+	exported, missing, err := exportArtifacts(&out, artifacts, paths, read, chartReader(read), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"alpine:3.10", "jfrog/artifactory-jcr:7.4.1", "artifactory-jcr-2.2.0.tgz"}; !reflect.DeepEqual(exported, expected) {
+		t.Errorf("Expected exported artifacts %v, got %v", expected, exported)
+	}
+	if expected := []string{"redis:6.0.8", "postgresql-8.7.3.tgz"}; !reflect.DeepEqual(missing, expected) {
+		t.Errorf("Expected missing artifacts %v, got %v", expected, missing)
+	}
+
+	archive := readTarball(t, out.Bytes())
+	if string(archive["oci/oci-layout"]) != ociImageLayout {
+		t.Error("Unexpected oci-layout: " + string(archive["oci/oci-layout"]))
+	}
+	index := ociIndex{}
+	if err := json.Unmarshal(archive["oci/index.json"], &index); err != nil {
+		t.Fatal(err)
+	}
+	refs := make([]string, 0)
+	for _, manifest := range index.Manifests {
+		refs = append(refs, manifest.Annotations[ociRefNameAnnotation])
+		content, ok := archive[ociBlobPath(manifest.Digest)]
+		if !ok || sha256Digest(content) != manifest.Digest || int64(len(content)) != manifest.Size {
+			t.Error("Missing or wrong manifest blob of " + manifest.Annotations[ociRefNameAnnotation])
+		}
+		image := imageManifest{}
+		if err := json.Unmarshal(content, &image); err != nil {
+			t.Fatal(err)
+		}
+		for _, blob := range append([]ociDescriptor{image.Config}, image.Layers...) {
+			if content, ok := archive[ociBlobPath(blob.Digest)]; !ok || sha256Digest(content) != blob.Digest {
+				t.Error("Missing or wrong blob " + blob.Digest)
+			}
+		}
+	}
+	if expected := []string{"alpine:3.10", "docker.bintray.io/jfrog/artifactory-jcr:7.4.1"}; !reflect.DeepEqual(refs, expected) {
+		t.Errorf("Expected image references %v, got %v", expected, refs)
+	}
+
+	if !bytes.Equal(archive["helm/artifactory-jcr-2.2.0.tgz"], chartArchive) {
+		t.Error("Missing chart archive artifactory-jcr-2.2.0.tgz")
+	}
+	charts := struct {
+		Entries map[string][]struct {
+			Name    string   `json:"name"`
+			Version string   `json:"version"`
+			URLs    []string `json:"urls"`
+			Digest  string   `json:"digest"`
+		} `json:"entries"`
+	}{}
+	if err := yaml.Unmarshal(archive["helm/index.yaml"], &charts); err != nil {
+		t.Fatal(err)
+	}
+	entries := charts.Entries["artifactory-jcr"]
+	if len(entries) != 1 || entries[0].Version != "2.2.0" || !reflect.DeepEqual(entries[0].URLs, []string{"artifactory-jcr-2.2.0.tgz"}) ||
+		"sha256:"+entries[0].Digest != sha256Digest(chartArchive) {
+		t.Errorf("Unexpected Helm repository index:\n%s", archive["helm/index.yaml"])
+	}
+
+	sums := strings.Split(strings.TrimSpace(string(archive[checksumsFile])), "\n")
+	if len(sums) != len(archive)-1 {
+		t.Errorf("Expected checksums of %d files, got:\n%s", len(archive)-1, archive[checksumsFile])
+	}
+	for _, line := range sums {
+		splits := strings.SplitN(line, "  ", 2)
+		if len(splits) != 2 || "sha256:"+splits[0] != sha256Digest(archive[splits[1]]) {
+			t.Error("Wrong checksum: " + line)
+		}
+	}
+}
+
+func TestExportArtifactsCorruptBlob(t *testing.T) {
+	files := imageFiles(t, "docker-local/library/alpine/3.10", []byte("alpine config"), []byte("alpine layer"))
+	files["docker-local/library/alpine/3.10/sha256__"+strings.TrimPrefix(sha256Digest([]byte("alpine layer")), "sha256:")] = []byte("alpine LAYER")
+	read, paths := writeArtifactoryFiles(t, files)
+	artifacts := imageAndChartArtifacts(map[string]string{"alpine:3.10": "alpine:3.10"}, map[string]string{}, "docker-local")
+	var out bytes.Buffer
+	if _, _, err := exportArtifacts(&out, artifacts, paths, read, chartReader(read), nil); err == nil || !strings.Contains(err.Error(), "doesn't match its digest") {
+		t.Errorf("Expected a digest mismatch, got %v", err)
+	}
+}
+
+func TestExportArtifactsManifestList(t *testing.T) {
+	files, _ := multiArchImageFiles(t, "docker-local/library/alpine/3.12", map[string][]byte{
+		"linux/amd64":    []byte("amd64 config"),
+		"linux/arm64/v8": []byte("arm64 config"),
+	})
+	content := files["docker-local/library/alpine/3.12/"+dockerManifestListFile]
+	list := manifestList{}
+	if err := json.Unmarshal(content, &list); err != nil {
+		t.Fatal(err)
+	}
+	read, paths := writeArtifactoryFiles(t, files)
+	results := make([]rtutils.SearchResult, 0)
+	for _, p := range paths {
+		results = append(results, rtutils.SearchResult{Path: p})
+	}
+	// As export does, the folders of the platform images are added to the artifacts.
+	artifacts := imageAndChartArtifacts(map[string]string{"alpine:3.12": "alpine:3.12"}, map[string]string{}, "docker-local")
+	artifacts, _, err := expandManifestLists(artifacts, results, read, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	exported, _, err := exportArtifacts(&out, artifacts, paths, read, chartReader(read), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(exported, []string{"alpine:3.12"}) {
+		t.Errorf("Expected the multi-architecture image to be exported, got %v", exported)
+	}
+	archive := readTarball(t, out.Bytes())
+	index := ociIndex{}
+	if err := json.Unmarshal(archive["oci/index.json"], &index); err != nil {
+		t.Fatal(err)
+	}
+	if len(index.Manifests) != 1 || index.Manifests[0].MediaType != list.MediaType || index.Manifests[0].Digest != sha256Digest(content) {
+		t.Fatalf("Expected the manifest list in the index, got %+v", index.Manifests)
+	}
+	if !bytes.Equal(archive[ociBlobPath(sha256Digest(content))], content) {
+		t.Error("Missing manifest list blob")
+	}
+	for _, platform := range list.Manifests {
+		image := imageManifest{}
+		if err := json.Unmarshal(archive[ociBlobPath(platform.Digest)], &image); err != nil {
+			t.Fatalf("Missing manifest of %s: %s", platform.Platform.Architecture, err)
+		}
+		for _, blob := range append([]ociDescriptor{image.Config}, image.Layers...) {
+			if content, ok := archive[ociBlobPath(blob.Digest)]; !ok || sha256Digest(content) != blob.Digest {
+				t.Error("Missing or wrong blob " + blob.Digest)
+			}
+		}
+	}
+
+	// A manifest list can't be exported without the image of each platform.
+	missing := make([]string, 0)
+	for _, p := range paths {
+		if !strings.Contains(p, strings.ReplaceAll(list.Manifests[1].Digest, ":", "__")) {
+			missing = append(missing, p)
+		}
+	}
+	if _, _, err := exportArtifacts(&out, artifacts, missing, read, chartReader(read), nil); err == nil || !strings.Contains(err.Error(), "which was not found") {
+		t.Errorf("Expected a missing platform image, got %v", err)
+	}
+}
+
+func TestExportArtifactsChartSize(t *testing.T) {
+	content, err := ioutil.ReadFile("testdata/artifactory-jcr-2.2.0.tgz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	downloads := 0
+	server := chartStandIn(t, sha256Hex(content), true, &downloads)
+	defer server.Close()
+	rtDetails := &config.ArtifactoryDetails{Url: server.URL + "/artifactory/"}
+	artifacts := imageAndChartArtifacts(map[string]string{}, map[string]string{"helm-local/artifactory-jcr-2.2.0.tgz": "artifactory-jcr-2.2.0.tgz"}, "docker-local")
+	paths := []string{"helm-local/artifactory-jcr-2.2.0.tgz"}
+	for _, test := range []struct {
+		maxSize  int64
+		expected string
+	}{{1 << 20, ""}, {1 << 10, "larger than the maximum size"}} {
+		readChart := func(p string) (*chart.Chart, []byte, error) {
+			return readChartArchive(rtDetails, p, nil, test.maxSize)
+		}
+		var out bytes.Buffer
+		// Only a chart is exported, so no other file is read.
+		_, _, err := exportArtifacts(&out, artifacts, paths, nil, readChart, nil)
+		if test.expected == "" && err != nil {
+			t.Errorf("Error exporting chart of at most %d bytes: %s", test.maxSize, err)
+		}
+		if test.expected != "" && (err == nil || !strings.Contains(err.Error(), test.expected)) {
+			t.Errorf("Expected the chart to exceed %d bytes, got %v", test.maxSize, err)
+		}
+	}
+	if downloads != 2 {
+		t.Errorf("Expected the chart to be downloaded twice, got %d downloads", downloads)
+	}
+}
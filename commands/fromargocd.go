@@ -0,0 +1,575 @@
+package commands
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/ghodss/yaml"
+	rtcommands "github.com/jfrog/jfrog-cli-core/artifactory/commands"
+	"github.com/jfrog/jfrog-cli-core/plugins/components"
+	"github.com/jfrog/jfrog-cli-core/utils/config"
+	distributionServicesUtils "github.com/jfrog/jfrog-client-go/distribution/services/utils"
+	"github.com/jfrog/jfrog-client-go/utils/errorutils"
+	"github.com/jfrog/jfrog-client-go/utils/log"
+	"io/ioutil"
+	"k8s.io/helm/pkg/chartutil"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+	"k8s.io/helm/pkg/renderutil"
+	"k8s.io/helm/pkg/strvals"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+type ArgoCDCommand struct {
+	rtDetails            *config.ArtifactoryDetails
+	releaseBundlesParams distributionServicesUtils.ReleaseBundleParams
+	bundleOptions        bundleOptions
+	appPaths             []string
+	manifestsDir         string
+	helmRepo             string
+	dockerRepo           string
+	dryRun               bool
+}
+
+// The subset of the Argo CD Application and ApplicationSet resources the
+// generator needs in order to know what an application deploys.
+type argoManifest struct {
+	Kind     string       `json:"kind"`
+	Metadata argoMetadata `json:"metadata"`
+}
+
+type argoMetadata struct {
+	Name string `json:"name"`
+}
+
+type argoApplication struct {
+	Metadata argoMetadata        `json:"metadata"`
+	Spec     argoApplicationSpec `json:"spec"`
+}
+
+type argoApplicationSpec struct {
+	Source      *argoSource     `json:"source,omitempty"`
+	Sources     []argoSource    `json:"sources,omitempty"`
+	Destination argoDestination `json:"destination"`
+}
+
+type argoDestination struct {
+	Namespace string `json:"namespace,omitempty"`
+}
+
+type argoSource struct {
+	RepoURL        string               `json:"repoURL"`
+	Chart          string               `json:"chart,omitempty"`
+	Path           string               `json:"path,omitempty"`
+	TargetRevision string               `json:"targetRevision,omitempty"`
+	Helm           *argoHelmSource      `json:"helm,omitempty"`
+	Directory      *argoDirectorySource `json:"directory,omitempty"`
+	// Ref names a source which other sources of a multi-source application
+	// read value files from, as in $values/charts/app/values.yaml.
+	Ref string `json:"ref,omitempty"`
+}
+
+type argoHelmSource struct {
+	ReleaseName  string                 `json:"releaseName,omitempty"`
+	ValueFiles   []string               `json:"valueFiles,omitempty"`
+	Values       string                 `json:"values,omitempty"`
+	ValuesObject map[string]interface{} `json:"valuesObject,omitempty"`
+	Parameters   []argoHelmParameter    `json:"parameters,omitempty"`
+}
+
+type argoHelmParameter struct {
+	Name        string `json:"name"`
+	Value       string `json:"value"`
+	ForceString bool   `json:"forceString,omitempty"`
+}
+
+type argoDirectorySource struct {
+	Recurse bool `json:"recurse,omitempty"`
+}
+
+type argoApplicationSet struct {
+	Metadata argoMetadata           `json:"metadata"`
+	Spec     argoApplicationSetSpec `json:"spec"`
+}
+
+type argoApplicationSetSpec struct {
+	Generators []argoGenerator `json:"generators"`
+	Template   json.RawMessage `json:"template"`
+}
+
+type argoGenerator struct {
+	List *argoListGenerator `json:"list,omitempty"`
+}
+
+type argoListGenerator struct {
+	Elements []map[string]interface{} `json:"elements"`
+}
+
+var (
+	argoTemplateParam = regexp.MustCompile(`{{\s*([\w.\-]+)\s*}}`)
+	yamlDocSeparator  = regexp.MustCompile(`(?m)^---\s*$`)
+)
+
+// Loads a chart archive, given its path in Artifactory.
+type chartLoader func(path string) (*chart.Chart, error)
+
+func GetReleaseBundleFromArgoCDCommand() components.Command {
+	return components.Command{
+		Name:        "from-argocd",
+		Description: "Generate a release bundle from Argo CD Application and ApplicationSet manifests.",
+		Aliases:     []string{"fa"},
+		Arguments:   getReleaseBundleTranslateChartArguments(),
+		Flags:       getReleaseBundleFromArgoCDFlags(),
+		EnvVars:     []components.EnvVar{},
+		Action: func(c *components.Context) error {
+			return releaseBundleFromArgoCDCmd(c)
+		},
+	}
+}
+
+func getReleaseBundleFromArgoCDFlags() []components.Flag {
+	flags := append(getArtifactoryFlags(),
+		components.StringFlag{
+			Name:        "app-path",
+			Description: "Semicolon-separated list of local Argo CD Application or ApplicationSet YAML files.",
+			Mandatory:   true,
+		},
+		components.StringFlag{
+			Name:        "docker-repo",
+			Description: "A Docker repository containing all the Docker images the applications require.",
+			Mandatory:   true,
+		},
+		components.StringFlag{
+			Name:        "helm-repo",
+			Description: "A Helm repository containing all the Helm charts the applications require. If not provided, it is taken from each application's repoURL.",
+		},
+		components.StringFlag{
+			Name:         "manifests-dir",
+			Description:  "Local checkout of the Git repository that application source paths are relative to.",
+			DefaultValue: ".",
+		})
+	return append(flags, getReleaseBundleFlags()...)
+}
+
+func releaseBundleFromArgoCDCmd(c *components.Context) error {
+	apppaths := c.GetStringFlagValue("app-path")
+	dockerrepo := c.GetStringFlagValue("docker-repo")
+	if !(len(c.Arguments) == 2 && apppaths != "" && dockerrepo != "") {
+		return errors.New("Wrong number of arguments.")
+	}
+	params, err := createReleaseBundleCreateUpdateParams(c, c.Arguments[0], c.Arguments[1])
+	if err != nil {
+		return err
+	}
+	options, err := createBundleOptions(c)
+	if err != nil {
+		return err
+	}
+	argoCmd := NewArgoCDCommand()
+	rtDetails, err := createArtifactoryDetailsByFlags(c)
+	if err != nil {
+		return err
+	}
+	argoCmd.SetRtDetails(rtDetails).SetReleaseBundleCreateParams(params).SetBundleOptions(options).SetAppPaths(strings.Split(apppaths, ";")).
+		SetManifestsDir(c.GetStringFlagValue("manifests-dir")).SetHelmRepo(c.GetStringFlagValue("helm-repo")).
+		SetDockerRepo(dockerrepo).SetDryRun(c.GetBoolFlagValue("dry-run"))
+	return rtcommands.Exec(argoCmd)
+}
+
+func NewArgoCDCommand() *ArgoCDCommand {
+	return &ArgoCDCommand{}
+}
+
+func (ac *ArgoCDCommand) SetRtDetails(rtDetails *config.ArtifactoryDetails) *ArgoCDCommand {
+	ac.rtDetails = rtDetails
+	return ac
+}
+
+func (ac *ArgoCDCommand) SetReleaseBundleCreateParams(params distributionServicesUtils.ReleaseBundleParams) *ArgoCDCommand {
+	ac.releaseBundlesParams = params
+	return ac
+}
+
+func (ac *ArgoCDCommand) SetBundleOptions(options bundleOptions) *ArgoCDCommand {
+	ac.bundleOptions = options
+	return ac
+}
+
+func (ac *ArgoCDCommand) SetAppPaths(appPaths []string) *ArgoCDCommand {
+	ac.appPaths = appPaths
+	return ac
+}
+
+func (ac *ArgoCDCommand) SetManifestsDir(manifestsDir string) *ArgoCDCommand {
+	ac.manifestsDir = manifestsDir
+	return ac
+}
+
+func (ac *ArgoCDCommand) SetHelmRepo(helmRepo string) *ArgoCDCommand {
+	ac.helmRepo = helmRepo
+	return ac
+}
+
+func (ac *ArgoCDCommand) SetDockerRepo(dockerRepo string) *ArgoCDCommand {
+	ac.dockerRepo = dockerRepo
+	return ac
+}
+
+func (ac *ArgoCDCommand) SetDryRun(dryRun bool) *ArgoCDCommand {
+	ac.dryRun = dryRun
+	return ac
+}
+
+func (ac *ArgoCDCommand) Run() error {
+	apps := make([]argoApplication, 0)
+	for _, path := range ac.appPaths {
+		content, err := ioutil.ReadFile(strings.TrimSpace(path))
+		if err != nil {
+			return errorutils.CheckError(err)
+		}
+		parsed, err := parseArgoApplications(content)
+		if err != nil {
+			return err
+		}
+		apps = append(apps, parsed...)
+	}
+	options := ac.bundleOptions
+	options.chartChecksums = map[string]string{}
+	loader := func(path string) (*chart.Chart, error) {
+		chrt, checksum, err := loadChartArchive(ac.rtDetails, path, options.cache, options.maxChartSize)
+		if err != nil {
+			return nil, err
+		}
+		options.chartChecksums[path] = checksum
+		return chrt, nil
+	}
+	origins := originIndex{}
+	images, charts, err := resolveArgoApplications(apps, loader, ac.helmRepo, ac.manifestsDir, origins)
+	if err != nil {
+		return err
+	}
+	expected := attachOrigins(imageAndChartArtifacts(images, charts, ac.dockerRepo), origins)
+	return createBundleAndReport(ac.rtDetails, ac.releaseBundlesParams, options, expected, ac.dryRun)
+}
+
+func (ac *ArgoCDCommand) RtDetails() (*config.ArtifactoryDetails, error) {
+	return ac.rtDetails, nil
+}
+
+func (ac *ArgoCDCommand) CommandName() string {
+	return "rt_translate_argocd"
+}
+
+// parseArgoApplications reads every Application in a (possibly multi-document)
+// YAML file. ApplicationSets are expanded into the Applications they generate.
+func parseArgoApplications(content []byte) ([]argoApplication, error) {
+	apps := make([]argoApplication, 0)
+	for _, doc := range splitYamlDocuments(string(content)) {
+		manifest := new(argoManifest)
+		if err := yaml.Unmarshal([]byte(doc), manifest); err != nil {
+			return nil, errorutils.CheckError(err)
+		}
+		switch manifest.Kind {
+		case "Application":
+			app := argoApplication{}
+			if err := yaml.Unmarshal([]byte(doc), &app); err != nil {
+				return nil, errorutils.CheckError(err)
+			}
+			apps = append(apps, app)
+		case "ApplicationSet":
+			appset := argoApplicationSet{}
+			if err := yaml.Unmarshal([]byte(doc), &appset); err != nil {
+				return nil, errorutils.CheckError(err)
+			}
+			generated, err := expandArgoApplicationSet(appset)
+			if err != nil {
+				return nil, err
+			}
+			apps = append(apps, generated...)
+		case "":
+			continue
+		default:
+			log.Debug("Skipping " + manifest.Kind + " " + manifest.Metadata.Name)
+		}
+	}
+	return apps, nil
+}
+
+// expandArgoApplicationSet substitutes the parameters of each list generator
+// element into the ApplicationSet template. Other generators depend on the
+// state of a cluster or a Git server, so they are skipped.
+func expandArgoApplicationSet(appset argoApplicationSet) ([]argoApplication, error) {
+	apps := make([]argoApplication, 0)
+	for _, generator := range appset.Spec.Generators {
+		if generator.List == nil {
+			log.Warn("ApplicationSet " + appset.Metadata.Name + " uses a generator other than list, which is not supported.")
+			continue
+		}
+		for _, element := range generator.List.Elements {
+			var err error
+			template := argoTemplateParam.ReplaceAllStringFunc(string(appset.Spec.Template), func(param string) string {
+				key := argoTemplateParam.FindStringSubmatch(param)[1]
+				value, ok := element[key]
+				if !ok {
+					err = errorutils.CheckError(fmt.Errorf("ApplicationSet %s: no value for parameter %s", appset.Metadata.Name, key))
+					return param
+				}
+				escaped, _ := json.Marshal(fmt.Sprint(value))
+				return string(escaped[1 : len(escaped)-1])
+			})
+			if err != nil {
+				return nil, err
+			}
+			app := argoApplication{}
+			if err = json.Unmarshal([]byte(template), &app); err != nil {
+				return nil, errorutils.CheckError(err)
+			}
+			apps = append(apps, app)
+		}
+	}
+	return apps, nil
+}
+
+// resolveArgoApplications renders each application's sources, and returns the
+// Docker images and Helm chart archives they deploy.
+func resolveArgoApplications(apps []argoApplication, loader chartLoader, helmrepo, manifestsDir string, origins originIndex) (map[string]string, map[string]string, error) {
+	images := map[string]string{}
+	charts := map[string]string{}
+	for _, app := range apps {
+		sources := app.Spec.Sources
+		if app.Spec.Source != nil {
+			sources = append([]argoSource{*app.Spec.Source}, sources...)
+		}
+		refs := map[string]argoSource{}
+		for _, source := range sources {
+			if source.Ref != "" {
+				refs[source.Ref] = source
+			}
+		}
+		for _, source := range sources {
+			var err error
+			switch {
+			case source.Chart != "":
+				err = resolveArgoChartSource(app, source, loader, helmrepo, manifestsDir, refs, images, charts, origins)
+			case source.Path != "":
+				err = resolveArgoPathSource(app, source, helmrepo, manifestsDir, refs, images, charts, origins)
+			case source.Ref != "":
+				// The source only provides value files to the other sources.
+			default:
+				err = errorutils.CheckError(errors.New("Application " + app.Metadata.Name + " has a source with neither a chart nor a path."))
+			}
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	return images, charts, nil
+}
+
+func resolveArgoChartSource(app argoApplication, source argoSource, loader chartLoader, helmrepo, manifestsDir string, refs map[string]argoSource, images, charts map[string]string, origins originIndex) error {
+	if helmrepo == "" {
+		helmrepo = helmRepoFromURL(source.RepoURL)
+	}
+	if helmrepo == "" {
+		return errorutils.CheckError(errors.New("Application " + app.Metadata.Name + ": cannot tell the Helm repository of " + source.RepoURL + ", use --helm-repo."))
+	}
+	chrt, err := loader(helmrepo + "/" + source.Chart + "-" + source.TargetRevision + ".tgz")
+	if err != nil {
+		return err
+	}
+	files, err := renderArgoHelmSource(app, source, chrt, argoValueFileReader(app, refs, manifestsDir, func(name string) ([]byte, error) {
+		return readChartFile(chrt, name)
+	}))
+	if err != nil {
+		return err
+	}
+	for k, v := range extractImages(files) {
+		images[k] = v
+	}
+	addChartArchives(charts, chrt, helmrepo)
+	origins.addChartOrigins(chrt, files, helmrepo, argoValuesProfile(source))
+	return nil
+}
+
+func resolveArgoPathSource(app argoApplication, source argoSource, helmrepo, manifestsDir string, refs map[string]argoSource, images, charts map[string]string, origins originIndex) error {
+	dir := filepath.Join(manifestsDir, filepath.FromSlash(source.Path))
+	if _, err := os.Stat(filepath.Join(dir, "Chart.yaml")); err != nil {
+		files, err := readManifests(dir, source.Directory != nil && source.Directory.Recurse)
+		if err != nil {
+			return err
+		}
+		for k, v := range extractImages(files) {
+			images[k] = v
+		}
+		return nil
+	}
+	chrt, err := chartutil.LoadDir(dir)
+	if err != nil {
+		return err
+	}
+	files, err := renderArgoHelmSource(app, source, chrt, argoValueFileReader(app, refs, manifestsDir, func(name string) ([]byte, error) {
+		return ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+	}))
+	if err != nil {
+		return err
+	}
+	for k, v := range extractImages(files) {
+		images[k] = v
+	}
+	origins.addChartOrigins(chrt, files, "", argoValuesProfile(source))
+	// The chart itself lives in Git, but its dependencies are pulled from a Helm repository.
+	if helmrepo == "" {
+		skipped := make([]string, 0)
+		for _, dep := range chrt.GetDependencies() {
+			skipped = append(skipped, dep.Metadata.Name+"-"+dep.Metadata.Version)
+		}
+		if len(skipped) > 0 {
+			log.Warn("Application " + app.Metadata.Name + ": skipping the dependencies of chart " + chrt.Metadata.Name + ", as no Helm repository is given: " + strings.Join(skipped, ", "))
+		}
+		return nil
+	}
+	for _, dep := range chrt.GetDependencies() {
+		addChartArchives(charts, dep, helmrepo)
+		origins.addArchiveOrigins(dep, chrt.Metadata.Name, helmrepo, argoValuesProfile(source))
+	}
+	return nil
+}
+
+// argoValueFileReader reads the value files of a Helm source with read, except
+// for those given as $ref/path, which are read from the source named ref. Like
+// the paths of sources, their paths are relative to manifestsDir, the checkout
+// of the Git repository of that source.
+func argoValueFileReader(app argoApplication, refs map[string]argoSource, manifestsDir string, read func(name string) ([]byte, error)) func(name string) ([]byte, error) {
+	return func(name string) ([]byte, error) {
+		if !strings.HasPrefix(name, "$") {
+			return read(name)
+		}
+		splits := strings.SplitN(strings.TrimPrefix(name, "$"), "/", 2)
+		ref, ok := refs[splits[0]]
+		if !ok || len(splits) < 2 {
+			return nil, errors.New("Application " + app.Metadata.Name + ": the value file " + name + " refers to no source.")
+		}
+		if ref.Chart != "" {
+			return nil, errors.New("Application " + app.Metadata.Name + ": the value file " + name + " is read from a Helm chart source, which is not supported.")
+		}
+		return ioutil.ReadFile(filepath.Join(manifestsDir, filepath.FromSlash(splits[1])))
+	}
+}
+
+// renderArgoHelmSource renders a chart the way Argo CD would: value files
+// first, then inline values, then parameters, each overriding the previous.
+func renderArgoHelmSource(app argoApplication, source argoSource, chrt *chart.Chart, readValueFile func(name string) ([]byte, error)) (map[string]string, error) {
+	values := chartutil.Values{}
+	releaseName := app.Metadata.Name
+	if helm := source.Helm; helm != nil {
+		for _, name := range helm.ValueFiles {
+			content, err := readValueFile(name)
+			if err != nil {
+				return nil, errorutils.CheckError(err)
+			}
+			fileValues, err := chartutil.ReadValues(content)
+			if err != nil {
+				return nil, errorutils.CheckError(err)
+			}
+			values.MergeInto(fileValues)
+		}
+		inlineValues, err := chartutil.ReadValues([]byte(helm.Values))
+		if err != nil {
+			return nil, errorutils.CheckError(err)
+		}
+		values.MergeInto(inlineValues)
+		values.MergeInto(helm.ValuesObject)
+		for _, param := range helm.Parameters {
+			if param.ForceString {
+				err = strvals.ParseIntoString(param.Name+"="+param.Value, values)
+			} else {
+				err = strvals.ParseInto(param.Name+"="+param.Value, values)
+			}
+			if err != nil {
+				return nil, errorutils.CheckError(err)
+			}
+		}
+		if helm.ReleaseName != "" {
+			releaseName = helm.ReleaseName
+		}
+	}
+	raw, err := values.YAML()
+	if err != nil {
+		return nil, errorutils.CheckError(err)
+	}
+	options := renderutil.Options{
+		ReleaseOptions: chartutil.ReleaseOptions{
+			Name:      releaseName,
+			Namespace: app.Spec.Destination.Namespace,
+		},
+	}
+	return renderutil.Render(chrt, &chart.Config{Raw: raw}, options)
+}
+
+// argoValuesProfile names the values a Helm source is rendered with by its
+// value files.
+func argoValuesProfile(source argoSource) string {
+	if source.Helm == nil || len(source.Helm.ValueFiles) == 0 {
+		return defaultValuesProfile
+	}
+	return strings.Join(source.Helm.ValueFiles, ",")
+}
+
+// helmRepoFromURL returns the Artifactory repository of a Helm repository URL
+// such as https://acme.jfrog.io/artifactory/api/helm/helm-virtual.
+func helmRepoFromURL(url string) string {
+	splits := strings.SplitN(url, "/api/helm/", 2)
+	if len(splits) < 2 {
+		return ""
+	}
+	return strings.SplitN(strings.Trim(splits[1], "/"), "/", 2)[0]
+}
+
+func readChartFile(chrt *chart.Chart, name string) ([]byte, error) {
+	if name == chartutil.ValuesfileName && chrt.Values != nil {
+		return []byte(chrt.Values.Raw), nil
+	}
+	for _, file := range chrt.Files {
+		if file.TypeUrl == name {
+			return file.Value, nil
+		}
+	}
+	return nil, errors.New("chart " + chrt.Metadata.Name + " has no file " + name)
+}
+
+func readManifests(dir string, recurse bool) (map[string]string, error) {
+	files := map[string]string{}
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if path != dir && !recurse {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".yaml", ".yml", ".json":
+			content, err := ioutil.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			files[path] = string(content)
+		}
+		return nil
+	})
+	return files, errorutils.CheckError(err)
+}
+
+func splitYamlDocuments(content string) []string {
+	docs := make([]string, 0)
+	for _, doc := range yamlDocSeparator.Split(content, -1) {
+		if strings.TrimSpace(doc) != "" {
+			docs = append(docs, doc)
+		}
+	}
+	return docs
+}
@@ -0,0 +1,129 @@
+package commands
+
+import (
+	"bytes"
+	"github.com/jfrog/jfrog-client-go/utils/log"
+	"io/ioutil"
+	"k8s.io/helm/pkg/chartutil"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+	"strings"
+	"testing"
+)
+
+func testdataChartLoader(path string) (*chart.Chart, error) {
+	return chartutil.Load("testdata/" + strings.SplitN(path, "/", 2)[1])
+}
+
+func TestArgoCDApplication(t *testing.T) {
+	expected := "{\"files\":[{\"pattern\":\"testdockerrepo/alpine/3.10/\"},{\"pattern\":\"testdockerrepo/*/alpine/3.10/\"},{\"pattern\":\"testdockerrepo/jfrog/artifactory-jcr/7.5.0/\"},{\"pattern\":\"testdockerrepo/*/jfrog/artifactory-jcr/7.5.0/\"},{\"pattern\":\"testdockerrepo/jfrog/nginx-artifactory-pro/7.4.1/\"},{\"pattern\":\"testdockerrepo/*/jfrog/nginx-artifactory-pro/7.4.1/\"},{\"pattern\":\"testhelmrepo/artifactory-9.4.0.tgz\"},{\"pattern\":\"testhelmrepo/*/artifactory-9.4.0.tgz\"},{\"pattern\":\"testhelmrepo/artifactory-jcr-2.2.0.tgz\"},{\"pattern\":\"testhelmrepo/*/artifactory-jcr-2.2.0.tgz\"}]}"
+	content, err := ioutil.ReadFile("testdata/argocd/apps.yaml")
+	if err != nil {
+		t.Fatalf("Error reading test application: %s\n", err)
+	}
+	apps, err := parseArgoApplications(content)
+	if err != nil {
+		t.Fatalf("Error parsing test application: %s\n", err)
+	}
+	if len(apps) != 1 {
+		t.Fatalf("Expected 1 application, got %d\n", len(apps))
+	}
+	images, charts, err := resolveArgoApplications(apps, testdataChartLoader, "", ".", originIndex{})
+	if err != nil {
+		t.Fatalf("Error resolving test application: %s\n", err)
+	}
+	specfiles, _ := buildFilespec(images, charts, "testdockerrepo")
+	spec := serializeSpecFiles(specfiles)
+	if spec != expected {
+		t.Fatalf("Generated spec is incorrect. Expected:\n%s\nGot:\n%s\n", expected, spec)
+	}
+}
+
+func TestArgoCDApplicationSet(t *testing.T) {
+	expected := "{\"files\":[{\"pattern\":\"otherhelmrepo/acs-engine-autoscaler-2.2.2.tgz\"},{\"pattern\":\"otherhelmrepo/*/acs-engine-autoscaler-2.2.2.tgz\"}]}"
+	content, err := ioutil.ReadFile("testdata/argocd/appset.yaml")
+	if err != nil {
+		t.Fatalf("Error reading test application set: %s\n", err)
+	}
+	apps, err := parseArgoApplications(content)
+	if err != nil {
+		t.Fatalf("Error parsing test application set: %s\n", err)
+	}
+	if len(apps) != 1 || apps[0].Metadata.Name != "east-autoscaler" || apps[0].Spec.Destination.Namespace != "east" {
+		t.Fatalf("Application set was not expanded correctly: %+v\n", apps)
+	}
+	images, charts, err := resolveArgoApplications(apps, testdataChartLoader, "otherhelmrepo", ".", originIndex{})
+	if err != nil {
+		t.Fatalf("Error resolving test application set: %s\n", err)
+	}
+	specfiles, _ := buildFilespec(images, charts, "testdockerrepo")
+	spec := serializeSpecFiles(specfiles)
+	if spec != expected {
+		t.Fatalf("Generated spec is incorrect. Expected:\n%s\nGot:\n%s\n", expected, spec)
+	}
+}
+
+func TestArgoCDMultiSourceApplication(t *testing.T) {
+	expected := "{\"files\":[{\"pattern\":\"testdockerrepo/alpine/3.10/\"},{\"pattern\":\"testdockerrepo/*/alpine/3.10/\"},{\"pattern\":\"testdockerrepo/jfrog/artifactory-jcr/7.5.0/\"},{\"pattern\":\"testdockerrepo/*/jfrog/artifactory-jcr/7.5.0/\"},{\"pattern\":\"testdockerrepo/jfrog/nginx-artifactory-pro/7.4.1/\"},{\"pattern\":\"testdockerrepo/*/jfrog/nginx-artifactory-pro/7.4.1/\"},{\"pattern\":\"testhelmrepo/artifactory-9.4.0.tgz\"},{\"pattern\":\"testhelmrepo/*/artifactory-9.4.0.tgz\"},{\"pattern\":\"testhelmrepo/artifactory-jcr-2.2.0.tgz\"},{\"pattern\":\"testhelmrepo/*/artifactory-jcr-2.2.0.tgz\"}]}"
+	content, err := ioutil.ReadFile("testdata/argocd/multisource.yaml")
+	if err != nil {
+		t.Fatalf("Error reading test application: %s\n", err)
+	}
+	apps, err := parseArgoApplications(content)
+	if err != nil {
+		t.Fatalf("Error parsing test application: %s\n", err)
+	}
+	// The value files of the chart source are read from the source which only sets ref.
+	images, charts, err := resolveArgoApplications(apps, testdataChartLoader, "", "testdata", originIndex{})
+	if err != nil {
+		t.Fatalf("Error resolving test application: %s\n", err)
+	}
+	specfiles, _ := buildFilespec(images, charts, "testdockerrepo")
+	spec := serializeSpecFiles(specfiles)
+	if spec != expected {
+		t.Fatalf("Generated spec is incorrect. Expected:\n%s\nGot:\n%s\n", expected, spec)
+	}
+
+	apps[0].Spec.Sources[0].Helm.ValueFiles = []string{"$other/argocd/values-jcr.yaml"}
+	_, _, err = resolveArgoApplications(apps, testdataChartLoader, "", "testdata", originIndex{})
+	if err == nil || !strings.Contains(err.Error(), "the value file $other/argocd/values-jcr.yaml refers to no source") {
+		t.Fatalf("Expected an error for a value file of an unknown source, got %v\n", err)
+	}
+}
+
+func TestArgoCDPathSource(t *testing.T) {
+	content, err := ioutil.ReadFile("testdata/argocd/pathsource.yaml")
+	if err != nil {
+		t.Fatalf("Error reading test application: %s\n", err)
+	}
+	apps, err := parseArgoApplications(content)
+	if err != nil {
+		t.Fatalf("Error parsing test application: %s\n", err)
+	}
+	// The chart is read from the Git checkout, and its dependency from the Helm repository.
+	expected := "{\"files\":[{\"pattern\":\"testdockerrepo/alpine/3.10/\"},{\"pattern\":\"testdockerrepo/*/alpine/3.10/\"},{\"pattern\":\"testdockerrepo/redis/6.0.8/\"},{\"pattern\":\"testdockerrepo/*/redis/6.0.8/\"},{\"pattern\":\"testhelmrepo/cache-0.1.0.tgz\"},{\"pattern\":\"testhelmrepo/*/cache-0.1.0.tgz\"}]}"
+	images, charts, err := resolveArgoApplications(apps, testdataChartLoader, "testhelmrepo", "testdata", originIndex{})
+	if err != nil {
+		t.Fatalf("Error resolving test application: %s\n", err)
+	}
+	specfiles, _ := buildFilespec(images, charts, "testdockerrepo")
+	spec := serializeSpecFiles(specfiles)
+	if spec != expected {
+		t.Fatalf("Generated spec is incorrect. Expected:\n%s\nGot:\n%s\n", expected, spec)
+	}
+
+	// Without a Helm repository, the skipped dependency is logged.
+	previous := log.Logger
+	defer log.SetLogger(previous)
+	logs := &bytes.Buffer{}
+	log.SetLogger(log.NewLogger(log.WARN, logs))
+	_, charts, err = resolveArgoApplications(apps, testdataChartLoader, "", "testdata", originIndex{})
+	if err != nil {
+		t.Fatalf("Error resolving test application: %s\n", err)
+	}
+	if len(charts) != 0 {
+		t.Fatalf("Expected no charts without a Helm repository, got %v\n", charts)
+	}
+	if !strings.Contains(logs.String(), "skipping the dependencies of chart platform, as no Helm repository is given: cache-0.1.0") {
+		t.Fatalf("Expected a warning for the skipped dependency, got:\n%s\n", logs.String())
+	}
+}
@@ -0,0 +1,335 @@
+package commands
+
+import (
+	"errors"
+	rtcommands "github.com/jfrog/jfrog-cli-core/artifactory/commands"
+	"github.com/jfrog/jfrog-cli-core/plugins/components"
+	"github.com/jfrog/jfrog-cli-core/utils/config"
+	distributionServicesUtils "github.com/jfrog/jfrog-client-go/distribution/services/utils"
+	"github.com/jfrog/jfrog-client-go/utils/errorutils"
+	"io/ioutil"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+type DockerfileCommand struct {
+	rtDetails            *config.ArtifactoryDetails
+	releaseBundlesParams distributionServicesUtils.ReleaseBundleParams
+	bundleOptions        bundleOptions
+	dockerfilePaths      []string
+	buildArgs            map[string]string
+	dockerRepo           string
+	dryRun               bool
+}
+
+var (
+	dockerfileEscapeDirective = regexp.MustCompile(`^#\s*escape\s*=\s*(\S)`)
+	dockerfileVariable        = regexp.MustCompile(`\$(?:([A-Za-z_][A-Za-z0-9_]*)|\{([A-Za-z_][A-Za-z0-9_]*)(?::([-+])([^}]*))?\})`)
+)
+
+func GetReleaseBundleFromDockerfileCommand() components.Command {
+	return components.Command{
+		Name:        "from-dockerfile",
+		Description: "Generate a release bundle from the base images of Dockerfiles.",
+		Aliases:     []string{"fd"},
+		Arguments:   getReleaseBundleTranslateChartArguments(),
+		Flags:       getReleaseBundleFromDockerfileFlags(),
+		EnvVars:     []components.EnvVar{},
+		Action: func(c *components.Context) error {
+			return releaseBundleFromDockerfileCmd(c)
+		},
+	}
+}
+
+func getReleaseBundleFromDockerfileFlags() []components.Flag {
+	flags := append(getArtifactoryFlags(),
+		components.StringFlag{
+			Name:        "dockerfile",
+			Description: "Semicolon-separated list of local Dockerfiles.",
+			Mandatory:   true,
+		},
+		components.StringFlag{
+			Name:        "build-arg",
+			Description: "Semicolon-separated list of build arguments, as in NAME=value, used to resolve ARG instructions.",
+		},
+		components.StringFlag{
+			Name:        "docker-repo",
+			Description: "A Docker repository containing all the Docker images the Dockerfiles require.",
+			Mandatory:   true,
+		})
+	return append(flags, getReleaseBundleFlags()...)
+}
+
+func releaseBundleFromDockerfileCmd(c *components.Context) error {
+	dockerfiles := c.GetStringFlagValue("dockerfile")
+	dockerrepo := c.GetStringFlagValue("docker-repo")
+	if !(len(c.Arguments) == 2 && dockerfiles != "" && dockerrepo != "") {
+		return errors.New("Wrong number of arguments.")
+	}
+	params, err := createReleaseBundleCreateUpdateParams(c, c.Arguments[0], c.Arguments[1])
+	if err != nil {
+		return err
+	}
+	options, err := createBundleOptions(c)
+	if err != nil {
+		return err
+	}
+	dockerfileCmd := NewDockerfileCommand()
+	rtDetails, err := createArtifactoryDetailsByFlags(c)
+	if err != nil {
+		return err
+	}
+	buildArgs := map[string]string{}
+	if args := c.GetStringFlagValue("build-arg"); args != "" {
+		for _, arg := range strings.Split(args, ";") {
+			splits := strings.SplitN(arg, "=", 2)
+			if len(splits) != 2 {
+				return errors.New("--build-arg must be a semicolon-separated list of NAME=value pairs.")
+			}
+			buildArgs[strings.TrimSpace(splits[0])] = splits[1]
+		}
+	}
+	dockerfileCmd.SetRtDetails(rtDetails).SetReleaseBundleCreateParams(params).SetBundleOptions(options).SetDockerfilePaths(strings.Split(dockerfiles, ";")).
+		SetBuildArgs(buildArgs).SetDockerRepo(dockerrepo).SetDryRun(c.GetBoolFlagValue("dry-run"))
+	return rtcommands.Exec(dockerfileCmd)
+}
+
+func NewDockerfileCommand() *DockerfileCommand {
+	return &DockerfileCommand{}
+}
+
+func (dc *DockerfileCommand) SetRtDetails(rtDetails *config.ArtifactoryDetails) *DockerfileCommand {
+	dc.rtDetails = rtDetails
+	return dc
+}
+
+func (dc *DockerfileCommand) SetReleaseBundleCreateParams(params distributionServicesUtils.ReleaseBundleParams) *DockerfileCommand {
+	dc.releaseBundlesParams = params
+	return dc
+}
+
+func (dc *DockerfileCommand) SetBundleOptions(options bundleOptions) *DockerfileCommand {
+	dc.bundleOptions = options
+	return dc
+}
+
+func (dc *DockerfileCommand) SetDockerfilePaths(dockerfilePaths []string) *DockerfileCommand {
+	dc.dockerfilePaths = dockerfilePaths
+	return dc
+}
+
+func (dc *DockerfileCommand) SetBuildArgs(buildArgs map[string]string) *DockerfileCommand {
+	dc.buildArgs = buildArgs
+	return dc
+}
+
+func (dc *DockerfileCommand) SetDockerRepo(dockerRepo string) *DockerfileCommand {
+	dc.dockerRepo = dockerRepo
+	return dc
+}
+
+func (dc *DockerfileCommand) SetDryRun(dryRun bool) *DockerfileCommand {
+	dc.dryRun = dryRun
+	return dc
+}
+
+func (dc *DockerfileCommand) Run() error {
+	images := map[string]string{}
+	for _, path := range dc.dockerfilePaths {
+		content, err := ioutil.ReadFile(strings.TrimSpace(path))
+		if err != nil {
+			return errorutils.CheckError(err)
+		}
+		refs, err := extractDockerfileImages(string(content), dc.buildArgs)
+		if err != nil {
+			return err
+		}
+		for _, ref := range refs {
+			images[ref] = ref
+		}
+	}
+	expected := dockerfileImageArtifacts(images, dc.dockerRepo)
+	return createBundleAndReport(dc.rtDetails, dc.releaseBundlesParams, dc.bundleOptions, expected, dc.dryRun)
+}
+
+// dockerfileImageArtifacts creates the artifacts of images. Unlike the images
+// of charts, their first component is only dropped if it's a registry, so that
+// library/alpine:3.13 is looked up as library/alpine/3.13/.
+func dockerfileImageArtifacts(images map[string]string, dockerrepo string) []bundleArtifact {
+	artifacts := make([]bundleArtifact, 0)
+	for _, ref := range sortStringMap(images) {
+		artifacts = append(artifacts, newImageArtifact(ref, parseImageReference(ref), dockerrepo))
+	}
+	return artifacts
+}
+
+func (dc *DockerfileCommand) RtDetails() (*config.ArtifactoryDetails, error) {
+	return dc.rtDetails, nil
+}
+
+func (dc *DockerfileCommand) CommandName() string {
+	return "rt_translate_dockerfile"
+}
+
+// extractDockerfileImages returns every external image a Dockerfile uses, in
+// FROM instructions and in COPY --from and RUN --mount=from= flags. Names and
+// indexes of earlier build stages are not images, and neither is scratch.
+func extractDockerfileImages(content string, buildArgs map[string]string) ([]string, error) {
+	images := make([]string, 0)
+	// ARG instructions before the first FROM can be used in FROM instructions.
+	globalArgs := map[string]string{}
+	stages := map[string]bool{}
+	stageCount := 0
+	addImage := func(ref string, args map[string]string) error {
+		ref, err := expandDockerfileVariables(ref, args)
+		if err != nil {
+			return err
+		}
+		if !stages[strings.ToLower(ref)] && strings.ToLower(ref) != "scratch" {
+			images = append(images, ref)
+		}
+		return nil
+	}
+	stageArgs := globalArgs
+	for _, instruction := range splitDockerfileInstructions(content) {
+		fields := strings.Fields(instruction)
+		if len(fields) < 2 {
+			continue
+		}
+		keyword, flags, args := strings.ToUpper(fields[0]), dockerfileFlags(fields[1:]), dockerfileArgs(fields[1:])
+		switch keyword {
+		case "ARG":
+			for _, arg := range args {
+				splits := strings.SplitN(arg, "=", 2)
+				name := splits[0]
+				if value, ok := buildArgs[name]; ok {
+					stageArgs[name] = value
+				} else if len(splits) == 2 {
+					value, err := expandDockerfileVariables(strings.Trim(splits[1], "\"'"), stageArgs)
+					if err != nil {
+						return nil, err
+					}
+					stageArgs[name] = value
+				} else if value, ok := globalArgs[name]; ok {
+					// Redeclaring a global ARG inside a stage makes its value available there.
+					stageArgs[name] = value
+				}
+			}
+		case "FROM":
+			if len(args) == 0 {
+				continue
+			}
+			if err := addImage(args[0], globalArgs); err != nil {
+				return nil, err
+			}
+			if len(args) >= 3 && strings.ToUpper(args[1]) == "AS" {
+				stages[strings.ToLower(args[2])] = true
+			}
+			stages[strconv.Itoa(stageCount)] = true
+			stageCount++
+			stageArgs = map[string]string{}
+		case "COPY", "RUN":
+			for _, flag := range flags {
+				from := ""
+				if strings.HasPrefix(flag, "--from=") {
+					from = strings.TrimPrefix(flag, "--from=")
+				} else if strings.HasPrefix(flag, "--mount=") {
+					for _, option := range strings.Split(strings.TrimPrefix(flag, "--mount="), ",") {
+						if strings.HasPrefix(option, "from=") {
+							from = strings.TrimPrefix(option, "from=")
+						}
+					}
+				}
+				if from == "" {
+					continue
+				}
+				merged := map[string]string{}
+				for k, v := range globalArgs {
+					merged[k] = v
+				}
+				for k, v := range stageArgs {
+					merged[k] = v
+				}
+				if err := addImage(from, merged); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	return images, nil
+}
+
+// splitDockerfileInstructions joins continuation lines, and drops comments and
+// parser directives.
+func splitDockerfileInstructions(content string) []string {
+	escape := "\\"
+	instructions := make([]string, 0)
+	current := ""
+	for i, line := range strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if i == 0 {
+			if match := dockerfileEscapeDirective.FindStringSubmatch(trimmed); match != nil {
+				escape = match[1]
+			}
+		}
+		if strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if strings.HasSuffix(trimmed, escape) {
+			current = current + strings.TrimSuffix(trimmed, escape) + " "
+			continue
+		}
+		current = strings.TrimSpace(current + trimmed)
+		if current != "" {
+			instructions = append(instructions, current)
+		}
+		current = ""
+	}
+	if current = strings.TrimSpace(current); current != "" {
+		instructions = append(instructions, current)
+	}
+	return instructions
+}
+
+func dockerfileFlags(fields []string) []string {
+	flags := make([]string, 0)
+	for _, field := range fields {
+		if !strings.HasPrefix(field, "--") {
+			break
+		}
+		flags = append(flags, field)
+	}
+	return flags
+}
+
+func dockerfileArgs(fields []string) []string {
+	return fields[len(dockerfileFlags(fields)):]
+}
+
+// expandDockerfileVariables substitutes $NAME, ${NAME}, ${NAME:-default} and
+// ${NAME:+alternative}, as Docker does in FROM instructions.
+func expandDockerfileVariables(value string, args map[string]string) (string, error) {
+	var err error
+	expanded := dockerfileVariable.ReplaceAllStringFunc(value, func(variable string) string {
+		match := dockerfileVariable.FindStringSubmatch(variable)
+		name := match[1] + match[2]
+		resolved, ok := args[name]
+		switch match[3] {
+		case "-":
+			if !ok || resolved == "" {
+				return match[4]
+			}
+		case "+":
+			if ok && resolved != "" {
+				return match[4]
+			}
+			return ""
+		}
+		if !ok {
+			err = errorutils.CheckError(errors.New("Dockerfile uses the undefined build argument " + name + " in " + value + ", use --build-arg."))
+		}
+		return resolved
+	})
+	return expanded, err
+}
@@ -0,0 +1,59 @@
+package commands
+
+import (
+	"io/ioutil"
+	"reflect"
+	"testing"
+)
+
+func TestDockerfileImages(t *testing.T) {
+	content, err := ioutil.ReadFile("testdata/dockerfiles/Dockerfile")
+	if err != nil {
+		t.Fatalf("Error reading test Dockerfile: %s\n", err)
+	}
+	tests := []struct {
+		buildArgs map[string]string
+		expected  []string
+	}{
+		{map[string]string{}, []string{
+			"docker.io/library/golang:1.15-alpine",
+			"acme.jfrog.io/tools/protoc:3.13",
+			"gcr.io/distroless/static",
+			"quay.io/prometheus/busybox@sha256:2548dd93c438f7cf8b68dc2ff140189d9bcdae7130d3941524becc31573ec9e3",
+		}},
+		{map[string]string{"GO_VERSION": "1.16", "BASE_IMAGE": "alpine:3.13"}, []string{
+			"docker.io/library/golang:1.16-alpine",
+			"acme.jfrog.io/tools/protoc:3.13",
+			"alpine:3.13",
+			"quay.io/prometheus/busybox@sha256:2548dd93c438f7cf8b68dc2ff140189d9bcdae7130d3941524becc31573ec9e3",
+		}},
+	}
+	for _, test := range tests {
+		images, err := extractDockerfileImages(string(content), test.buildArgs)
+		if err != nil {
+			t.Fatalf("Error parsing test Dockerfile: %s\n", err)
+		}
+		if !reflect.DeepEqual(images, test.expected) {
+			t.Fatalf("Extracted images are incorrect. Expected:\n%v\nGot:\n%v\n", test.expected, images)
+		}
+	}
+}
+
+func TestDockerfileUndefinedArg(t *testing.T) {
+	_, err := extractDockerfileImages("ARG TAG\nFROM alpine:${TAG}\n", map[string]string{})
+	if err == nil {
+		t.Fatalf("Expected an error for an undefined build argument\n")
+	}
+}
+
+func TestDockerfileSpec(t *testing.T) {
+	expected := "{\"files\":[{\"pattern\":\"testdockerrepo/library/alpine/3.13/\"},{\"pattern\":\"testdockerrepo/*/library/alpine/3.13/\"},{\"pattern\":\"testdockerrepo/prometheus/busybox/sha256__2548dd93c438f7cf8b68dc2ff140189d9bcdae7130d3941524becc31573ec9e3/\"},{\"pattern\":\"testdockerrepo/*/prometheus/busybox/sha256__2548dd93c438f7cf8b68dc2ff140189d9bcdae7130d3941524becc31573ec9e3/\"}]}"
+	images := map[string]string{
+		"library/alpine:3.13": "library/alpine:3.13",
+		"quay.io/prometheus/busybox@sha256:2548dd93c438f7cf8b68dc2ff140189d9bcdae7130d3941524becc31573ec9e3": "quay.io/prometheus/busybox@sha256:2548dd93c438f7cf8b68dc2ff140189d9bcdae7130d3941524becc31573ec9e3",
+	}
+	spec := serializeSpecFiles(createSpecFiles(dockerfileImageArtifacts(images, "testdockerrepo")))
+	if spec != expected {
+		t.Fatalf("Generated spec is incorrect. Expected:\n%s\nGot:\n%s\n", expected, spec)
+	}
+}
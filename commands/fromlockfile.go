@@ -0,0 +1,170 @@
+package commands
+
+import (
+	"errors"
+	rtcommands "github.com/jfrog/jfrog-cli-core/artifactory/commands"
+	"github.com/jfrog/jfrog-cli-core/plugins/components"
+	"github.com/jfrog/jfrog-cli-core/utils/config"
+	distributionServicesUtils "github.com/jfrog/jfrog-client-go/distribution/services/utils"
+	"github.com/jfrog/jfrog-client-go/utils/errorutils"
+	"io/ioutil"
+	"strings"
+)
+
+type LockfileCommand struct {
+	rtDetails            *config.ArtifactoryDetails
+	releaseBundlesParams distributionServicesUtils.ReleaseBundleParams
+	bundleOptions        bundleOptions
+	lockfilePaths        []string
+	packageRepos         map[string]string
+	dryRun               bool
+}
+
+func GetReleaseBundleFromLockfileCommand() components.Command {
+	return components.Command{
+		Name:        "from-lockfile",
+		Description: "Generate a release bundle from package lockfiles, such as package-lock.json, pom.xml, go.sum, requirements.txt or poetry.lock.",
+		Aliases:     []string{"fl"},
+		Arguments:   getReleaseBundleTranslateChartArguments(),
+		Flags:       getReleaseBundleFromLockfileFlags(),
+		EnvVars:     []components.EnvVar{},
+		Action: func(c *components.Context) error {
+			return releaseBundleFromLockfileCmd(c)
+		},
+	}
+}
+
+func getReleaseBundleFromLockfileFlags() []components.Flag {
+	flags := append(getArtifactoryFlags(),
+		components.StringFlag{
+			Name:        "lockfile",
+			Description: "Semicolon-separated list of local lockfiles. The format is detected by the file name, or can be given as a prefix, as in maven:dependency-tree.txt.",
+			Mandatory:   true,
+		},
+		components.StringFlag{
+			Name:        "npm-repo",
+			Description: "An npm repository containing all the packages listed in package-lock.json files.",
+		},
+		components.StringFlag{
+			Name:        "maven-repo",
+			Description: "A Maven repository containing all the dependencies listed in pom.xml files or Maven dependency trees.",
+		},
+		components.StringFlag{
+			Name:        "go-repo",
+			Description: "A Go repository containing all the modules listed in go.sum files.",
+		},
+		components.StringFlag{
+			Name:        "pypi-repo",
+			Description: "A PyPI repository containing all the packages listed in requirements.txt or poetry.lock files.",
+		})
+	return append(flags, getReleaseBundleFlags()...)
+}
+
+func releaseBundleFromLockfileCmd(c *components.Context) error {
+	lockfiles := c.GetStringFlagValue("lockfile")
+	if !(len(c.Arguments) == 2 && lockfiles != "") {
+		return errors.New("Wrong number of arguments.")
+	}
+	params, err := createReleaseBundleCreateUpdateParams(c, c.Arguments[0], c.Arguments[1])
+	if err != nil {
+		return err
+	}
+	options, err := createBundleOptions(c)
+	if err != nil {
+		return err
+	}
+	lockfileCmd := NewLockfileCommand()
+	rtDetails, err := createArtifactoryDetailsByFlags(c)
+	if err != nil {
+		return err
+	}
+	repos := map[string]string{}
+	for _, packageType := range []string{"npm", "maven", "go", "pypi"} {
+		repos[packageType] = c.GetStringFlagValue(packageType + "-repo")
+	}
+	lockfileCmd.SetRtDetails(rtDetails).SetReleaseBundleCreateParams(params).SetBundleOptions(options).SetLockfilePaths(strings.Split(lockfiles, ";")).
+		SetPackageRepos(repos).SetDryRun(c.GetBoolFlagValue("dry-run"))
+	return rtcommands.Exec(lockfileCmd)
+}
+
+func NewLockfileCommand() *LockfileCommand {
+	return &LockfileCommand{}
+}
+
+func (lc *LockfileCommand) SetRtDetails(rtDetails *config.ArtifactoryDetails) *LockfileCommand {
+	lc.rtDetails = rtDetails
+	return lc
+}
+
+func (lc *LockfileCommand) SetReleaseBundleCreateParams(params distributionServicesUtils.ReleaseBundleParams) *LockfileCommand {
+	lc.releaseBundlesParams = params
+	return lc
+}
+
+func (lc *LockfileCommand) SetBundleOptions(options bundleOptions) *LockfileCommand {
+	lc.bundleOptions = options
+	return lc
+}
+
+func (lc *LockfileCommand) SetLockfilePaths(lockfilePaths []string) *LockfileCommand {
+	lc.lockfilePaths = lockfilePaths
+	return lc
+}
+
+// SetPackageRepos sets the repository of each package type: npm, maven, go and pypi.
+func (lc *LockfileCommand) SetPackageRepos(packageRepos map[string]string) *LockfileCommand {
+	lc.packageRepos = packageRepos
+	return lc
+}
+
+func (lc *LockfileCommand) SetDryRun(dryRun bool) *LockfileCommand {
+	lc.dryRun = dryRun
+	return lc
+}
+
+func (lc *LockfileCommand) Run() error {
+	artifacts, err := readLockfiles(lc.lockfilePaths, lc.packageRepos)
+	if err != nil {
+		return err
+	}
+	return createBundleAndReport(lc.rtDetails, lc.releaseBundlesParams, lc.bundleOptions, artifacts, lc.dryRun)
+}
+
+func (lc *LockfileCommand) RtDetails() (*config.ArtifactoryDetails, error) {
+	return lc.rtDetails, nil
+}
+
+func (lc *LockfileCommand) CommandName() string {
+	return "rt_translate_lockfile"
+}
+
+func readLockfiles(paths []string, repos map[string]string) ([]bundleArtifact, error) {
+	artifacts := make([]bundleArtifact, 0)
+	seen := map[string]bool{}
+	for _, path := range paths {
+		format, path, err := detectLockfileFormat(strings.TrimSpace(path))
+		if err != nil {
+			return nil, err
+		}
+		repo := repos[format.packageType]
+		if repo == "" {
+			return nil, errorutils.CheckError(errors.New("the --" + format.packageType + "-repo option is mandatory for " + path))
+		}
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, errorutils.CheckError(err)
+		}
+		parsed, err := format.parse(content, repo)
+		if err != nil {
+			return nil, err
+		}
+		for _, artifact := range parsed {
+			artifact.packageType = format.packageType
+			if !seen[artifact.name] {
+				seen[artifact.name] = true
+				artifacts = append(artifacts, artifact)
+			}
+		}
+	}
+	return artifacts, nil
+}
@@ -0,0 +1,270 @@
+package commands
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/ghodss/yaml"
+	rtcommands "github.com/jfrog/jfrog-cli-core/artifactory/commands"
+	"github.com/jfrog/jfrog-cli-core/artifactory/spec"
+	"github.com/jfrog/jfrog-cli-core/plugins/components"
+	"github.com/jfrog/jfrog-cli-core/utils/config"
+	"github.com/jfrog/jfrog-client-go/artifactory/services/utils"
+	distributionServicesUtils "github.com/jfrog/jfrog-client-go/distribution/services/utils"
+	"github.com/jfrog/jfrog-client-go/utils/errorutils"
+	"io/ioutil"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+	"k8s.io/helm/pkg/renderutil"
+	"strconv"
+)
+
+type ManifestCommand struct {
+	rtDetails            *config.ArtifactoryDetails
+	releaseBundlesParams distributionServicesUtils.ReleaseBundleParams
+	bundleOptions        bundleOptions
+	manifestPath         string
+	dryRun               bool
+}
+
+// bundleManifest lists the sources a release bundle is composed of.
+type bundleManifest struct {
+	DockerRepo string           `json:"dockerRepo"`
+	Sources    []manifestSource `json:"sources"`
+}
+
+// manifestSource is one source of a bundle manifest. Exactly one of Chart,
+// Images, Filespec and Bundle is set.
+type manifestSource struct {
+	// Chart is the path of a chart archive in Artifactory, as in helm/mychart-1.0.0.tgz.
+	Chart  string                 `json:"chart"`
+	Values map[string]interface{} `json:"values"`
+	// Profile names the values of the chart in origin properties.
+	Profile string `json:"profile"`
+	// Images are Docker image references, resolved against the Docker repository.
+	Images []string `json:"images"`
+	// Filespec is the path of a local file spec.
+	Filespec string `json:"filespec"`
+	// Bundle is an existing release bundle, as in name/version.
+	Bundle     string `json:"bundle"`
+	DockerRepo string `json:"dockerRepo"`
+}
+
+func GetReleaseBundleFromManifestCommand() components.Command {
+	return components.Command{
+		Name:        "from-manifest",
+		Description: "Generate a single release bundle from several charts, image lists, file specs and release bundles.",
+		Aliases:     []string{"fm", "compose"},
+		Arguments:   getReleaseBundleTranslateChartArguments(),
+		Flags:       getReleaseBundleFromManifestFlags(),
+		EnvVars:     []components.EnvVar{},
+		Action: func(c *components.Context) error {
+			return releaseBundleFromManifestCmd(c)
+		},
+	}
+}
+
+func getReleaseBundleFromManifestFlags() []components.Flag {
+	flags := append(getArtifactoryFlags(),
+		components.StringFlag{
+			Name:        "manifest",
+			Description: "Local YAML file listing the sources of the release bundle.",
+			Mandatory:   true,
+		})
+	return append(flags, getReleaseBundleFlags()...)
+}
+
+func releaseBundleFromManifestCmd(c *components.Context) error {
+	manifest := c.GetStringFlagValue("manifest")
+	if !(len(c.Arguments) == 2 && manifest != "") {
+		return errors.New("Wrong number of arguments.")
+	}
+	params, err := createReleaseBundleCreateUpdateParams(c, c.Arguments[0], c.Arguments[1])
+	if err != nil {
+		return err
+	}
+	options, err := createBundleOptions(c)
+	if err != nil {
+		return err
+	}
+	manifestCmd := NewManifestCommand()
+	rtDetails, err := createArtifactoryDetailsByFlags(c)
+	if err != nil {
+		return err
+	}
+	manifestCmd.SetRtDetails(rtDetails).SetReleaseBundleCreateParams(params).SetBundleOptions(options).SetManifestPath(manifest).SetDryRun(c.GetBoolFlagValue("dry-run"))
+	return rtcommands.Exec(manifestCmd)
+}
+
+func NewManifestCommand() *ManifestCommand {
+	return &ManifestCommand{}
+}
+
+func (mc *ManifestCommand) SetRtDetails(rtDetails *config.ArtifactoryDetails) *ManifestCommand {
+	mc.rtDetails = rtDetails
+	return mc
+}
+
+func (mc *ManifestCommand) SetReleaseBundleCreateParams(params distributionServicesUtils.ReleaseBundleParams) *ManifestCommand {
+	mc.releaseBundlesParams = params
+	return mc
+}
+
+func (mc *ManifestCommand) SetBundleOptions(options bundleOptions) *ManifestCommand {
+	mc.bundleOptions = options
+	return mc
+}
+
+func (mc *ManifestCommand) SetManifestPath(manifestPath string) *ManifestCommand {
+	mc.manifestPath = manifestPath
+	return mc
+}
+
+func (mc *ManifestCommand) SetDryRun(dryRun bool) *ManifestCommand {
+	mc.dryRun = dryRun
+	return mc
+}
+
+func (mc *ManifestCommand) Run() error {
+	manifest, err := readBundleManifest(mc.manifestPath)
+	if err != nil {
+		return err
+	}
+	options := mc.bundleOptions
+	options.chartChecksums = map[string]string{}
+	loader := func(path string) (*chart.Chart, error) {
+		chrt, checksum, err := loadChartArchive(mc.rtDetails, path, options.cache, options.maxChartSize)
+		if err != nil {
+			return nil, err
+		}
+		options.chartChecksums[path] = checksum
+		return chrt, nil
+	}
+	expected, err := resolveManifestSources(manifest, loader)
+	if err != nil {
+		return err
+	}
+	return createBundleAndReport(mc.rtDetails, mc.releaseBundlesParams, options, expected, mc.dryRun)
+}
+
+func (mc *ManifestCommand) RtDetails() (*config.ArtifactoryDetails, error) {
+	return mc.rtDetails, nil
+}
+
+func (mc *ManifestCommand) CommandName() string {
+	return "rt_translate_manifest"
+}
+
+func readBundleManifest(path string) (*bundleManifest, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errorutils.CheckError(err)
+	}
+	manifest := &bundleManifest{}
+	if err = yaml.Unmarshal(content, manifest); err != nil {
+		return nil, errorutils.CheckError(err)
+	}
+	return manifest, nil
+}
+
+// resolveManifestSources resolves every source of a bundle manifest, and merges
+// their artifacts in order, without duplicates.
+func resolveManifestSources(manifest *bundleManifest, loader chartLoader) ([]bundleArtifact, error) {
+	lists := make([][]bundleArtifact, 0)
+	for i, source := range manifest.Sources {
+		dockerrepo := source.DockerRepo
+		if dockerrepo == "" {
+			dockerrepo = manifest.DockerRepo
+		}
+		artifacts, err := resolveManifestSource(source, loader, dockerrepo)
+		if err != nil {
+			return nil, errorutils.CheckError(errors.New("Source " + describeManifestSource(i, source) + ": " + err.Error()))
+		}
+		lists = append(lists, artifacts)
+	}
+	return mergeArtifacts(lists...), nil
+}
+
+func resolveManifestSource(source manifestSource, loader chartLoader, dockerrepo string) ([]bundleArtifact, error) {
+	set := 0
+	for _, field := range []bool{source.Chart != "", len(source.Images) > 0, source.Filespec != "", source.Bundle != ""} {
+		if field {
+			set++
+		}
+	}
+	if set != 1 {
+		return nil, errors.New("exactly one of chart, images, filespec and bundle must be set.")
+	}
+	if (source.Chart != "" || len(source.Images) > 0) && dockerrepo == "" {
+		return nil, errors.New("no Docker repository, set dockerRepo.")
+	}
+	switch {
+	case source.Chart != "":
+		chrt, err := loader(source.Chart)
+		if err != nil {
+			return nil, err
+		}
+		values := []byte("{}")
+		if source.Values != nil {
+			values, err = yaml.Marshal(source.Values)
+			if err != nil {
+				return nil, err
+			}
+		}
+		files, err := renderutil.Render(chrt, &chart.Config{Raw: string(values)}, renderutil.Options{})
+		if err != nil {
+			return nil, err
+		}
+		charts := map[string]string{}
+		addChartArchives(charts, chrt, extractRepo(source.Chart))
+		origins := originIndex{}
+		origins.addChartOrigins(chrt, files, extractRepo(source.Chart), source.Profile)
+		return attachOrigins(imageAndChartArtifacts(extractImages(files), charts, dockerrepo), origins), nil
+	case len(source.Images) > 0:
+		images := map[string]string{}
+		for _, image := range source.Images {
+			images[image] = image
+		}
+		return imageAndChartArtifacts(images, map[string]string{}, dockerrepo), nil
+	case source.Filespec != "":
+		files, err := spec.CreateSpecFromFile(source.Filespec, nil)
+		if err != nil {
+			return nil, err
+		}
+		artifacts := make([]bundleArtifact, 0)
+		for _, file := range files.Files {
+			name := file.Pattern
+			if file.Aql.ItemsFind != "" {
+				name = "items.find(" + file.Aql.ItemsFind + ")"
+			}
+			artifacts = append(artifacts, bundleArtifact{name: name, files: []spec.File{file}})
+		}
+		return artifacts, nil
+	default:
+		name, version, err := parseBundleNameAndVersion(source.Bundle)
+		if err != nil {
+			return nil, err
+		}
+		return []bundleArtifact{newBundleArtifactFromBundle(name, version)}, nil
+	}
+}
+
+// newBundleArtifactFromBundle describes the contents of an existing release
+// bundle. The bundle file spec property only searches the root of the
+// repositories when used with a pattern, so an AQL query is used instead.
+func newBundleArtifactFromBundle(name, version string) bundleArtifact {
+	quotedName, _ := json.Marshal(name)
+	quotedVersion, _ := json.Marshal(version)
+	query := `{"$and":[{"release_artifact.release.name":` + string(quotedName) + `,"release_artifact.release.version":` + string(quotedVersion) + `}]}`
+	return bundleArtifact{name: "release bundle " + name + "/" + version, files: []spec.File{{Aql: utils.Aql{ItemsFind: query}}}}
+}
+
+func describeManifestSource(index int, source manifestSource) string {
+	switch {
+	case source.Chart != "":
+		return source.Chart
+	case source.Filespec != "":
+		return source.Filespec
+	case source.Bundle != "":
+		return source.Bundle
+	}
+	return "#" + strconv.Itoa(index+1)
+}
@@ -0,0 +1,44 @@
+package commands
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBundleManifest(t *testing.T) {
+	expected := "{\"files\":[{\"pattern\":\"testdockerrepo/alpine/3.10/\"},{\"pattern\":\"testdockerrepo/*/alpine/3.10/\"},{\"pattern\":\"testdockerrepo/jfrog/artifactory-jcr/7.4.1/\"},{\"pattern\":\"testdockerrepo/*/jfrog/artifactory-jcr/7.4.1/\"},{\"pattern\":\"testdockerrepo/jfrog/nginx-artifactory-pro/7.4.1/\"},{\"pattern\":\"testdockerrepo/*/jfrog/nginx-artifactory-pro/7.4.1/\"},{\"pattern\":\"testhelmrepo/artifactory-9.4.0.tgz\"},{\"pattern\":\"testhelmrepo/*/artifactory-9.4.0.tgz\"},{\"pattern\":\"testhelmrepo/artifactory-jcr-2.2.0.tgz\"},{\"pattern\":\"testhelmrepo/*/artifactory-jcr-2.2.0.tgz\"},{\"pattern\":\"otherhelmrepo/acs-engine-autoscaler-2.2.2.tgz\"},{\"pattern\":\"otherhelmrepo/*/acs-engine-autoscaler-2.2.2.tgz\"},{\"pattern\":\"otherdockerrepo/alpine/3.10/\"},{\"pattern\":\"otherdockerrepo/*/alpine/3.10/\"},{\"pattern\":\"otherdockerrepo/jfrog/xray-server/3.8.0/\"},{\"pattern\":\"otherdockerrepo/*/jfrog/xray-server/3.8.0/\"},{\"pattern\":\"generic-local/installers/*.zip\"},{\"aql\":{\"items.find\":{\"repo\":\"generic-local\",\"name\":{\"$match\":\"*.sh\"}}}},{\"aql\":{\"items.find\":{\"$and\":[{\"release_artifact.release.name\":\"platform\",\"release_artifact.release.version\":\"1.2.0\"}]}}}]}"
+	manifest, err := readBundleManifest("testdata/manifest/bundle.yaml")
+	if err != nil {
+		t.Fatalf("Error reading test manifest: %s\n", err)
+	}
+	artifacts, err := resolveManifestSources(manifest, testdataChartLoader)
+	if err != nil {
+		t.Fatalf("Error resolving test manifest: %s\n", err)
+	}
+	spec := serializeSpecFiles(createSpecFiles(artifacts))
+	if spec != expected {
+		t.Fatalf("Generated spec is incorrect. Expected:\n%s\nGot:\n%s\n", expected, spec)
+	}
+}
+
+func TestMergeArtifacts(t *testing.T) {
+	images := imageAndChartArtifacts(map[string]string{"alpine:3.10": "alpine:3.10"}, map[string]string{}, "docker")
+	bundle := newBundleArtifactFromBundle("platform", "1.2.0")
+	merged := mergeArtifacts(images, []bundleArtifact{bundle}, images, []bundleArtifact{bundle})
+	if len(merged) != 2 || merged[0].name != "alpine:3.10" || merged[1].name != "release bundle platform/1.2.0" {
+		t.Fatalf("Merged artifacts are incorrect: %v\n", merged)
+	}
+
+	// The origins of the artifacts which are dropped are kept.
+	app := artifactOrigin{chart: "app", valuesProfile: defaultValuesProfile}
+	db := artifactOrigin{chart: "db", parentChart: "app", valuesProfile: defaultValuesProfile}
+	first := attachOrigins(images, originIndex{"alpine:3.10": {app}})
+	second := attachOrigins(images, originIndex{"alpine:3.10": {db, app}})
+	merged = mergeArtifacts(first, second)
+	if len(merged) != 1 || !reflect.DeepEqual(merged[0].origins, []artifactOrigin{app, db}) {
+		t.Fatalf("Merged origins are incorrect: %+v\n", merged)
+	}
+	if len(first[0].origins) != 1 {
+		t.Fatalf("Merging changed the origins of the merged lists: %+v\n", first[0].origins)
+	}
+}
@@ -0,0 +1,264 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	rtcommands "github.com/jfrog/jfrog-cli-core/artifactory/commands"
+	"github.com/jfrog/jfrog-cli-core/artifactory/spec"
+	"github.com/jfrog/jfrog-cli-core/plugins/components"
+	"github.com/jfrog/jfrog-cli-core/utils/config"
+	"github.com/jfrog/jfrog-cli-core/utils/coreutils"
+	"github.com/jfrog/jfrog-client-go/utils/errorutils"
+	"io/ioutil"
+	"k8s.io/helm/pkg/chartutil"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+	"os"
+	"sort"
+	"strings"
+)
+
+type GenerateSpecCommand struct {
+	rtDetails    *config.ArtifactoryDetails
+	chartPath    string
+	helmRepo     string
+	dockerRepo   string
+	specOut      string
+	specVars     map[string]string
+	repoMappings []repoMapping
+	maxChartSize int64
+}
+
+func GetGenerateSpecCommand() components.Command {
+	return components.Command{
+		Name:        "generate-spec",
+		Description: "Generate the file spec of a release bundle from a Helm chart, without creating the bundle.",
+		Aliases:     []string{"gs"},
+		Arguments:   []components.Argument{},
+		Flags:       getGenerateSpecFlags(),
+		EnvVars:     []components.EnvVar{},
+		Action: func(c *components.Context) error {
+			return generateSpecCmd(c)
+		},
+	}
+}
+
+func getGenerateSpecFlags() []components.Flag {
+	return append(getArtifactoryFlags(),
+		components.StringFlag{
+			Name:        "chart-path",
+			Description: "Path to a Helm chart, either a local chart archive or directory, or a chart archive in Artifactory.",
+			Mandatory:   true,
+		},
+		components.StringFlag{
+			Name:        "docker-repo",
+			Description: "A Docker repository containing all the Docker images the Helm chart requires.",
+			Mandatory:   true,
+		},
+		components.StringFlag{
+			Name:        "helm-repo",
+			Description: "A Helm repository containing all the Helm charts the chart requires. Mandatory for a local chart, defaults to the repository of a chart in Artifactory.",
+		},
+		components.StringFlag{
+			Name:        "spec-out",
+			Description: "File to write the spec to, instead of the standard output.",
+		},
+		components.StringFlag{
+			Name:        "spec-vars",
+			Description: "List of variables in the form of \"key1=value1;key2=value2;...\". Every value found in the spec is replaced by ${key}, to be filled in with the --spec-vars option of jfrog rt rbc.",
+		},
+		getRepoMappingsFlag(),
+		getMaxChartSizeFlag())
+}
+
+func generateSpecCmd(c *components.Context) error {
+	chartpath := c.GetStringFlagValue("chart-path")
+	dockerrepo := c.GetStringFlagValue("docker-repo")
+	helmrepo := c.GetStringFlagValue("helm-repo")
+	if !(len(c.Arguments) == 0 && chartpath != "" && dockerrepo != "") {
+		return errors.New("Wrong number of arguments.")
+	}
+	mappings, err := parseRepoMappings(c.GetStringFlagValue("repo-mappings"))
+	if err != nil {
+		return err
+	}
+	maxChartSize, err := parseMaxChartSize(c.GetStringFlagValue("max-chart-size"))
+	if err != nil {
+		return err
+	}
+	generateSpecCmd := NewGenerateSpecCommand()
+	if _, err := os.Stat(chartpath); err == nil {
+		if helmrepo == "" {
+			return errors.New("the --helm-repo option is mandatory with a local chart")
+		}
+	} else {
+		rtDetails, err := createArtifactoryDetails(c, true)
+		if err != nil {
+			return err
+		}
+		if rtDetails.Url == "" {
+			return errors.New("the --url option is mandatory with a chart in Artifactory")
+		}
+		generateSpecCmd.SetRtDetails(rtDetails)
+	}
+	generateSpecCmd.SetChartPath(chartpath).SetHelmRepo(helmrepo).SetDockerRepo(dockerrepo).
+		SetSpecOut(c.GetStringFlagValue("spec-out")).SetSpecVars(coreutils.SpecVarsStringToMap(c.GetStringFlagValue("spec-vars"))).
+		SetRepoMappings(mappings).SetMaxChartSize(maxChartSize)
+	return rtcommands.Exec(generateSpecCmd)
+}
+
+func NewGenerateSpecCommand() *GenerateSpecCommand {
+	return &GenerateSpecCommand{}
+}
+
+func (gc *GenerateSpecCommand) SetRtDetails(rtDetails *config.ArtifactoryDetails) *GenerateSpecCommand {
+	gc.rtDetails = rtDetails
+	return gc
+}
+
+func (gc *GenerateSpecCommand) SetChartPath(chartPath string) *GenerateSpecCommand {
+	gc.chartPath = chartPath
+	return gc
+}
+
+func (gc *GenerateSpecCommand) SetHelmRepo(helmRepo string) *GenerateSpecCommand {
+	gc.helmRepo = helmRepo
+	return gc
+}
+
+func (gc *GenerateSpecCommand) SetDockerRepo(dockerRepo string) *GenerateSpecCommand {
+	gc.dockerRepo = dockerRepo
+	return gc
+}
+
+func (gc *GenerateSpecCommand) SetSpecOut(specOut string) *GenerateSpecCommand {
+	gc.specOut = specOut
+	return gc
+}
+
+func (gc *GenerateSpecCommand) SetSpecVars(specVars map[string]string) *GenerateSpecCommand {
+	gc.specVars = specVars
+	return gc
+}
+
+func (gc *GenerateSpecCommand) SetRepoMappings(repoMappings []repoMapping) *GenerateSpecCommand {
+	gc.repoMappings = repoMappings
+	return gc
+}
+
+func (gc *GenerateSpecCommand) SetMaxChartSize(maxChartSize int64) *GenerateSpecCommand {
+	gc.maxChartSize = maxChartSize
+	return gc
+}
+
+func (gc *GenerateSpecCommand) Run() error {
+	var chrt *chart.Chart
+	var err error
+	helmrepo := gc.helmRepo
+	if gc.rtDetails == nil {
+		chrt, err = chartutil.Load(gc.chartPath)
+	} else {
+		if helmrepo == "" {
+			helmrepo = extractRepo(gc.chartPath)
+		}
+		chrt, err = gc.readChart()
+	}
+	if err != nil {
+		return errorutils.CheckError(err)
+	}
+	_, artifacts, err := createFilespec(chrt, helmrepo, gc.dockerRepo)
+	if err != nil {
+		return err
+	}
+	specfiles := createSpecFiles(applyRepoMappings(artifacts, gc.repoMappings))
+	content, err := formatSpec(serializeSpecFiles(templateSpecVars(specfiles, gc.specVars)))
+	if err != nil {
+		return err
+	}
+	if gc.specOut == "" {
+		_, err = os.Stdout.Write(content)
+		return errorutils.CheckError(err)
+	}
+	return errorutils.CheckError(ioutil.WriteFile(gc.specOut, content, 0644))
+}
+
+func (gc *GenerateSpecCommand) readChart() (*chart.Chart, error) {
+	chrt, _, err := loadChartArchive(gc.rtDetails, gc.chartPath, nil, gc.maxChartSize)
+	return chrt, err
+}
+
+func (gc *GenerateSpecCommand) RtDetails() (*config.ArtifactoryDetails, error) {
+	return gc.rtDetails, nil
+}
+
+func (gc *GenerateSpecCommand) CommandName() string {
+	return "rt_generate_spec"
+}
+
+// templateSpecVars replaces the values of specVars in the patterns and targets
+// of specfiles with ${key}, the reverse of what jfrog rt rbc --spec-vars does.
+// Only whole path components are replaced, so that a version doesn't replace
+// part of a longer one. Longer values are replaced first, so they win over
+// values they contain.
+func templateSpecVars(specfiles *spec.SpecFiles, specVars map[string]string) *spec.SpecFiles {
+	if len(specVars) == 0 {
+		return specfiles
+	}
+	keys := make([]string, 0)
+	for key := range specVars {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if len(specVars[keys[i]]) != len(specVars[keys[j]]) {
+			return len(specVars[keys[i]]) > len(specVars[keys[j]])
+		}
+		return keys[i] < keys[j]
+	})
+	replace := func(path string) string {
+		for _, key := range keys {
+			if specVars[key] != "" {
+				path = replacePathComponents(path, specVars[key], "${"+key+"}")
+			}
+		}
+		return path
+	}
+	templated := &spec.SpecFiles{Files: make([]spec.File, 0)}
+	for _, file := range specfiles.Files {
+		file.Pattern = replace(file.Pattern)
+		file.Target = replace(file.Target)
+		templated.Files = append(templated.Files, file)
+	}
+	return templated
+}
+
+// replacePathComponents replaces the occurrences of value in path which start
+// and end at a slash or at an end of path.
+func replacePathComponents(path, value, replacement string) string {
+	replaced := ""
+	start := 0
+	for from := 0; from < len(path); {
+		i := strings.Index(path[from:], value)
+		if i < 0 {
+			break
+		}
+		i = i + from
+		end := i + len(value)
+		if (i == 0 || path[i-1] == '/') && (end == len(path) || path[end] == '/') {
+			replaced = replaced + path[start:i] + replacement
+			start = end
+			from = end
+		} else {
+			from = i + 1
+		}
+	}
+	return replaced + path[start:]
+}
+
+func formatSpec(specstr string) ([]byte, error) {
+	var out bytes.Buffer
+	if err := json.Indent(&out, []byte(specstr), "", "  "); err != nil {
+		return nil, errorutils.CheckError(err)
+	}
+	out.WriteString("\n")
+	return out.Bytes(), nil
+}
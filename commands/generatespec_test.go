@@ -0,0 +1,97 @@
+package commands
+
+import (
+	"github.com/jfrog/jfrog-cli-core/artifactory/spec"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestGenerateSpec(t *testing.T) {
+	expected := `{
+  "files": [
+    {
+      "pattern": "${DOCKER_REPO}/alpine/3.10/"
+    },
+    {
+      "pattern": "${DOCKER_REPO}/*/alpine/3.10/"
+    },
+    {
+      "pattern": "${DOCKER_REPO}/bitnami/postgresql/9.6.17-debian-10-r21/"
+    },
+    {
+      "pattern": "${DOCKER_REPO}/*/bitnami/postgresql/9.6.17-debian-10-r21/"
+    },
+    {
+      "pattern": "${DOCKER_REPO}/jfrog/artifactory-jcr/7.4.1/"
+    },
+    {
+      "pattern": "${DOCKER_REPO}/*/jfrog/artifactory-jcr/7.4.1/"
+    },
+    {
+      "pattern": "${DOCKER_REPO}/jfrog/nginx-artifactory-pro/7.4.1/"
+    },
+    {
+      "pattern": "${DOCKER_REPO}/*/jfrog/nginx-artifactory-pro/7.4.1/"
+    },
+    {
+      "pattern": "helm-local/artifactory-9.4.0.tgz"
+    },
+    {
+      "pattern": "helm-local/*/artifactory-9.4.0.tgz"
+    },
+    {
+      "pattern": "helm-local/artifactory-jcr-2.2.0.tgz"
+    },
+    {
+      "pattern": "helm-local/*/artifactory-jcr-2.2.0.tgz"
+    },
+    {
+      "pattern": "helm-local/postgresql-8.7.3.tgz"
+    },
+    {
+      "pattern": "helm-local/*/postgresql-8.7.3.tgz"
+    }
+  ]
+}
+`
+	dir, err := ioutil.TempDir("", "generate-spec")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	specOut := filepath.Join(dir, "spec.json")
+	err = NewGenerateSpecCommand().SetChartPath("testdata/artifactory-jcr-2.2.0.tgz").SetHelmRepo("helm-local").
+		SetDockerRepo("docker-local").SetSpecOut(specOut).SetSpecVars(map[string]string{"DOCKER_REPO": "docker-local", "DOCKER": "docker"}).Run()
+	if err != nil {
+		t.Fatalf("Error generating spec: %s\n", err)
+	}
+	spec, err := ioutil.ReadFile(specOut)
+	if err != nil {
+		t.Fatalf("Error reading generated spec: %s\n", err)
+	}
+	if string(spec) != expected {
+		t.Fatalf("Generated spec is incorrect. Expected:\n%s\nGot:\n%s\n", expected, spec)
+	}
+}
+
+func TestTemplateSpecVars(t *testing.T) {
+	specfiles := &spec.SpecFiles{Files: []spec.File{
+		{Pattern: "docker-local/alpine/1.0/"},
+		{Pattern: "docker-local/alpine/1.0.3/"},
+		{Pattern: "docker-local/alpine/11.0/", Target: "docker-local-edge/alpine/1.0/"},
+		{Pattern: "helm-local/alpine-1.0.tgz"},
+	}}
+	templated := templateSpecVars(specfiles, map[string]string{"VERSION": "1.0", "DOCKER_REPO": "docker-local"})
+	expected := []spec.File{
+		{Pattern: "${DOCKER_REPO}/alpine/${VERSION}/"},
+		{Pattern: "${DOCKER_REPO}/alpine/1.0.3/"},
+		{Pattern: "${DOCKER_REPO}/alpine/11.0/", Target: "docker-local-edge/alpine/${VERSION}/"},
+		{Pattern: "helm-local/alpine-1.0.tgz"},
+	}
+	if !reflect.DeepEqual(templated.Files, expected) {
+		t.Fatalf("Templated spec is incorrect. Expected:\n%+v\nGot:\n%+v\n", expected, templated.Files)
+	}
+}
@@ -0,0 +1,78 @@
+package commands
+
+import (
+	"strings"
+)
+
+// imageReference is a parsed Docker image reference, such as
+// docker.io/bitnami/postgresql:9.6.17 or alpine@sha256:<digest>.
+type imageReference struct {
+	registry   string
+	repository string
+	tag        string
+	digest     string
+}
+
+// parseImageReference splits an image reference the way Docker does: the first
+// component is a registry only if it looks like a host name.
+func parseImageReference(ref string) imageReference {
+	image := imageReference{}
+	if index := strings.Index(ref, "@"); index >= 0 {
+		image.digest = ref[index+1:]
+		ref = ref[:index]
+	}
+	if index := strings.LastIndex(ref, ":"); index >= 0 && !strings.Contains(ref[index:], "/") {
+		image.tag = ref[index+1:]
+		ref = ref[:index]
+	}
+	if splits := strings.SplitN(ref, "/", 2); len(splits) == 2 &&
+		(strings.ContainsAny(splits[0], ".:") || splits[0] == "localhost") {
+		image.registry = splits[0]
+		ref = splits[1]
+	}
+	image.repository = ref
+	if image.tag == "" && image.digest == "" {
+		image.tag = "latest"
+	}
+	return image
+}
+
+// parseChartImageReference parses an image reference the way from-chart always
+// has: its first component is dropped, whether it looks like a host name or
+// not, so that bitnami/postgresql:11 is looked up as postgresql/11/, and found
+// in any folder through the wildcard pattern of the artifact.
+func parseChartImageReference(ref string) imageReference {
+	image := parseImageReference(ref)
+	if image.registry == "" {
+		if splits := strings.SplitN(image.repository, "/", 2); len(splits) == 2 {
+			image.repository = splits[1]
+		}
+	}
+	return image
+}
+
+// newImageArtifact creates the artifact of the image ref, looked up in
+// dockerrepo as image.
+func newImageArtifact(ref string, image imageReference, dockerrepo string) bundleArtifact {
+	artifact := newBundleArtifact(image.name(), dockerrepo, image.path())
+	artifact.packageType = "docker"
+	artifact.source = ref
+	return artifact
+}
+
+// name returns the reference without its registry, as listed in the report.
+func (ir imageReference) name() string {
+	if ir.digest != "" {
+		return ir.repository + "@" + ir.digest
+	}
+	return ir.repository + ":" + ir.tag
+}
+
+// path returns the folder of the image in an Artifactory Docker repository.
+// Images pulled by digest are stored in a folder named after the digest.
+func (ir imageReference) path() string {
+	if ir.digest != "" {
+		return ir.repository + "/" + strings.ReplaceAll(ir.digest, ":", "__") + "/"
+	}
+	return ir.repository + "/" + ir.tag + "/"
+}
@@ -0,0 +1,548 @@
+package commands
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	rtcommands "github.com/jfrog/jfrog-cli-core/artifactory/commands"
+	"github.com/jfrog/jfrog-cli-core/plugins/components"
+	"github.com/jfrog/jfrog-cli-core/utils/config"
+	rthttpclient "github.com/jfrog/jfrog-client-go/artifactory/httpclient"
+	artifactoryUtils "github.com/jfrog/jfrog-client-go/artifactory/services/utils"
+	"github.com/jfrog/jfrog-client-go/auth"
+	distributionServicesUtils "github.com/jfrog/jfrog-client-go/distribution/services/utils"
+	clientutils "github.com/jfrog/jfrog-client-go/utils"
+	"github.com/jfrog/jfrog-client-go/utils/errorutils"
+	"github.com/jfrog/jfrog-client-go/utils/log"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+type ImportCommand struct {
+	rtDetails            *config.ArtifactoryDetails
+	releaseBundlesParams distributionServicesUtils.ReleaseBundleParams
+	bundleOptions        bundleOptions
+	archivePath          string
+	helmRepo             string
+	dockerRepo           string
+	createBundle         bool
+	dryRun               bool
+}
+
+// importTarget stores the charts and images of an archive.
+type importTarget interface {
+	uploadChart(repo, name, localPath string) error
+	hasBlob(repo, image, digest string) (bool, error)
+	uploadBlob(repo, image, digest, localPath string) error
+	putManifest(repo, image, reference, mediaType string, content []byte) error
+}
+
+// artifactoryImportTarget uploads charts to Artifactory, and pushes images
+// through the Docker registry API of its Docker repositories.
+type artifactoryImportTarget struct {
+	rtDetails *config.ArtifactoryDetails
+	client    *rthttpclient.ArtifactoryHttpClient
+	auth      auth.ServiceDetails
+}
+
+func GetImportCommand() components.Command {
+	return components.Command{
+		Name:        "import",
+		Description: "Import an archive created by the export command into Artifactory, and optionally recreate its release bundle.",
+		Aliases:     []string{"im"},
+		Arguments:   []components.Argument{},
+		Flags:       getImportFlags(),
+		EnvVars:     []components.EnvVar{},
+		Action: func(c *components.Context) error {
+			return importCmd(c)
+		},
+	}
+}
+
+func getImportFlags() []components.Flag {
+	flags := append(getArtifactoryFlags(),
+		components.StringFlag{
+			Name:        "archive",
+			Description: "Path to an archive created by the export command.",
+			Mandatory:   true,
+		},
+		components.StringFlag{
+			Name:        "helm-repo",
+			Description: "A Helm repository to upload the charts of the archive to.",
+			Mandatory:   true,
+		},
+		components.StringFlag{
+			Name:        "docker-repo",
+			Description: "A Docker repository to push the images of the archive to.",
+			Mandatory:   true,
+		},
+		components.BoolFlag{
+			Name:        "create-bundle",
+			Description: "Set to true to recreate the release bundle the archive was exported for, with its name, version, description and release notes, from the imported artifacts.",
+		})
+	return append(flags, getReleaseBundleFlags()...)
+}
+
+func importCmd(c *components.Context) error {
+	archive := c.GetStringFlagValue("archive")
+	helmrepo := c.GetStringFlagValue("helm-repo")
+	dockerrepo := c.GetStringFlagValue("docker-repo")
+	if !(len(c.Arguments) == 0 && archive != "" && helmrepo != "" && dockerrepo != "") {
+		return errors.New("Wrong number of arguments.")
+	}
+	// The name and version of the bundle are read from the archive.
+	params, err := createReleaseBundleCreateUpdateParams(c, "", "")
+	if err != nil {
+		return err
+	}
+	options, err := createBundleOptions(c)
+	if err != nil {
+		return err
+	}
+	rtDetails, err := createArtifactoryDetailsByFlags(c)
+	if err != nil {
+		return err
+	}
+	importCmd := NewImportCommand()
+	importCmd.SetRtDetails(rtDetails).SetReleaseBundleCreateParams(params).SetBundleOptions(options).SetArchivePath(archive).
+		SetHelmRepo(helmrepo).SetDockerRepo(dockerrepo).SetCreateBundle(c.GetBoolFlagValue("create-bundle")).SetDryRun(c.GetBoolFlagValue("dry-run"))
+	return rtcommands.Exec(importCmd)
+}
+
+func NewImportCommand() *ImportCommand {
+	return &ImportCommand{}
+}
+
+func (ic *ImportCommand) SetRtDetails(rtDetails *config.ArtifactoryDetails) *ImportCommand {
+	ic.rtDetails = rtDetails
+	return ic
+}
+
+func (ic *ImportCommand) SetReleaseBundleCreateParams(params distributionServicesUtils.ReleaseBundleParams) *ImportCommand {
+	ic.releaseBundlesParams = params
+	return ic
+}
+
+func (ic *ImportCommand) SetBundleOptions(options bundleOptions) *ImportCommand {
+	ic.bundleOptions = options
+	return ic
+}
+
+func (ic *ImportCommand) SetArchivePath(archivePath string) *ImportCommand {
+	ic.archivePath = archivePath
+	return ic
+}
+
+func (ic *ImportCommand) SetHelmRepo(helmRepo string) *ImportCommand {
+	ic.helmRepo = helmRepo
+	return ic
+}
+
+func (ic *ImportCommand) SetDockerRepo(dockerRepo string) *ImportCommand {
+	ic.dockerRepo = dockerRepo
+	return ic
+}
+
+func (ic *ImportCommand) SetCreateBundle(createBundle bool) *ImportCommand {
+	ic.createBundle = createBundle
+	return ic
+}
+
+func (ic *ImportCommand) SetDryRun(dryRun bool) *ImportCommand {
+	ic.dryRun = dryRun
+	return ic
+}
+
+func (ic *ImportCommand) Run() error {
+	dir, err := ioutil.TempDir("", "release-bundle-import")
+	if err != nil {
+		return errorutils.CheckError(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := extractArchive(ic.archivePath, dir); err != nil {
+		return err
+	}
+	if err := verifyChecksums(dir); err != nil {
+		return err
+	}
+	metadata, err := readBundleMetadata(dir)
+	if err != nil {
+		return err
+	}
+	if ic.createBundle && metadata == nil {
+		return errorutils.CheckError(errors.New("The archive has no release bundle to recreate, export it with --bundle."))
+	}
+	client, artAuth, err := createArtifactoryClient(ic.rtDetails)
+	if err != nil {
+		return err
+	}
+	target := &artifactoryImportTarget{rtDetails: ic.rtDetails, client: client, auth: artAuth}
+	artifacts, err := importArchive(dir, ic.helmRepo, ic.dockerRepo, target)
+	if err != nil {
+		return err
+	}
+	out := ic.bundleOptions.out
+	if out == nil {
+		out = os.Stdout
+	}
+	imported := make([]string, 0)
+	for _, artifact := range artifacts {
+		imported = append(imported, artifact.name)
+	}
+	printReport(out, "Imported:", imported, nil)
+	if !ic.createBundle {
+		return nil
+	}
+	return createBundleAndReport(ic.rtDetails, applyBundleMetadata(ic.releaseBundlesParams, metadata), ic.bundleOptions, artifacts, ic.dryRun)
+}
+
+func (ic *ImportCommand) RtDetails() (*config.ArtifactoryDetails, error) {
+	return ic.rtDetails, nil
+}
+
+func (ic *ImportCommand) CommandName() string {
+	return "rt_import"
+}
+
+// applyBundleMetadata names params after the release bundle of an archive, and
+// uses its description and release notes where params doesn't set them.
+func applyBundleMetadata(params distributionServicesUtils.ReleaseBundleParams, metadata *bundleMetadata) distributionServicesUtils.ReleaseBundleParams {
+	params.Name = metadata.Name
+	params.Version = metadata.Version
+	if params.Description == "" {
+		params.Description = metadata.Description
+	}
+	if params.ReleaseNotes == "" && metadata.ReleaseNotes != nil {
+		params.ReleaseNotes = metadata.ReleaseNotes.Content
+		params.ReleaseNotesSyntax = metadata.ReleaseNotes.Syntax
+	}
+	return params
+}
+
+// extractArchive extracts an archive, compressed or not, to dir.
+func extractArchive(archivePath, dir string) error {
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return errorutils.CheckError(err)
+	}
+	defer file.Close()
+	buffered := bufio.NewReader(file)
+	var r io.Reader = buffered
+	if magic, err := buffered.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(buffered)
+		if err != nil {
+			return errorutils.CheckError(err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return errorutils.CheckError(err)
+		}
+		if header.Typeflag == tar.TypeDir {
+			continue
+		}
+		name := path.Clean(header.Name)
+		if (header.Typeflag != tar.TypeReg && header.Typeflag != tar.TypeRegA) ||
+			path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+			return errorutils.CheckError(errors.New("Unexpected file " + header.Name + " in the archive."))
+		}
+		if err := extractFile(tr, filepath.Join(dir, filepath.FromSlash(name))); err != nil {
+			return err
+		}
+	}
+}
+
+func extractFile(r io.Reader, dest string) error {
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return errorutils.CheckError(err)
+	}
+	file, err := os.Create(dest)
+	if err != nil {
+		return errorutils.CheckError(err)
+	}
+	_, err = io.Copy(file, r)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return errorutils.CheckError(err)
+}
+
+// verifyChecksums checks that the files extracted to dir are those listed in
+// SHA256SUMS, with the same checksums, and that each OCI blob is named after
+// its digest.
+func verifyChecksums(dir string) error {
+	content, err := ioutil.ReadFile(filepath.Join(dir, checksumsFile))
+	if err != nil {
+		return errorutils.CheckError(errors.New("The archive has no " + checksumsFile + "."))
+	}
+	expected := map[string]string{}
+	for _, line := range strings.Split(strings.TrimSpace(string(content)), "\n") {
+		splits := strings.SplitN(line, "  ", 2)
+		if len(splits) != 2 {
+			return errorutils.CheckError(errors.New("Unexpected line in " + checksumsFile + ": " + line))
+		}
+		expected[splits[1]] = splits[0]
+	}
+	err = filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(rel)
+		if name == checksumsFile {
+			return nil
+		}
+		checksum, ok := expected[name]
+		if !ok {
+			return errors.New(name + " isn't listed in " + checksumsFile + ".")
+		}
+		delete(expected, name)
+		actual, err := fileChecksum(p)
+		if err != nil {
+			return err
+		}
+		if actual != checksum {
+			return errors.New("The checksum of " + name + " doesn't match " + checksumsFile + ".")
+		}
+		if strings.HasPrefix(name, ociLayoutDir+"/blobs/") && ociBlobPath("sha256:"+actual) != name {
+			return errors.New(name + " doesn't match its digest.")
+		}
+		return nil
+	})
+	if err != nil {
+		return errorutils.CheckError(err)
+	}
+	if len(expected) > 0 {
+		return errorutils.CheckError(errors.New("Files listed in " + checksumsFile + " are missing from the archive: " + strings.Join(sortedKeys(expected), ", ")))
+	}
+	return nil
+}
+
+func fileChecksum(p string) (string, error) {
+	file, err := os.Open(p)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func readBundleMetadata(dir string) (*bundleMetadata, error) {
+	content, err := ioutil.ReadFile(filepath.Join(dir, bundleMetadataFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errorutils.CheckError(err)
+	}
+	metadata := &bundleMetadata{}
+	return metadata, errorutils.CheckError(json.Unmarshal(content, metadata))
+}
+
+// importArchive uploads the charts and images extracted to dir to helmrepo
+// and dockerrepo, and returns them as artifacts of those repositories.
+func importArchive(dir, helmrepo, dockerrepo string, target importTarget) ([]bundleArtifact, error) {
+	artifacts := make([]bundleArtifact, 0)
+	charts, err := filepath.Glob(filepath.Join(dir, helmRepoDir, "*.tgz"))
+	if err != nil {
+		return nil, errorutils.CheckError(err)
+	}
+	sort.Strings(charts)
+	for _, chartPath := range charts {
+		name := filepath.Base(chartPath)
+		if err := target.uploadChart(helmrepo, name, chartPath); err != nil {
+			return nil, err
+		}
+		artifact := newBundleArtifact(name, helmrepo, name)
+		artifact.packageType = "helm"
+		artifact.source = helmrepo + "/" + name
+		artifacts = append(artifacts, artifact)
+	}
+	content, err := ioutil.ReadFile(filepath.Join(dir, ociLayoutDir, "index.json"))
+	if os.IsNotExist(err) {
+		return artifacts, nil
+	}
+	if err != nil {
+		return nil, errorutils.CheckError(err)
+	}
+	index := ociIndex{}
+	if err := json.Unmarshal(content, &index); err != nil {
+		return nil, errorutils.CheckError(err)
+	}
+	for _, descriptor := range index.Manifests {
+		ref := descriptor.Annotations[ociRefNameAnnotation]
+		if ref == "" {
+			return nil, errorutils.CheckError(errors.New("Image " + descriptor.Digest + " has no reference in the archive."))
+		}
+		image := parseImageReference(ref)
+		if err := importImage(dir, dockerrepo, image, descriptor, target); err != nil {
+			return nil, err
+		}
+		artifact := newBundleArtifact(image.name(), dockerrepo, image.path())
+		artifact.packageType = "docker"
+		artifact.source = ref
+		artifacts = append(artifacts, artifact)
+	}
+	return artifacts, nil
+}
+
+// importImage pushes the blobs of an image which aren't in dockerrepo yet, and
+// then its manifest, tagged as in its reference. The image of each platform of
+// a manifest list, or image index, is pushed by digest before the list itself.
+func importImage(dir, dockerrepo string, image imageReference, descriptor ociDescriptor, target importTarget) error {
+	reference := image.tag
+	if image.digest != "" {
+		reference = image.digest
+	}
+	content, err := ioutil.ReadFile(importBlobPath(dir, descriptor.Digest))
+	if err != nil {
+		return errorutils.CheckError(err)
+	}
+	list := manifestList{}
+	if err := json.Unmarshal(content, &list); err != nil {
+		return errorutils.CheckError(err)
+	}
+	if len(list.Manifests) == 0 {
+		return importManifest(dir, dockerrepo, image, descriptor.Digest, reference, descriptor.MediaType, target)
+	}
+	for _, platform := range list.Manifests {
+		if err := importManifest(dir, dockerrepo, image, platform.Digest, platform.Digest, "", target); err != nil {
+			return err
+		}
+	}
+	mediaType := descriptor.MediaType
+	if mediaType == "" {
+		mediaType = list.MediaType
+	}
+	if mediaType == "" {
+		mediaType = ociIndexMediaType
+	}
+	return target.putManifest(dockerrepo, image.repository, reference, mediaType, content)
+}
+
+// importManifest pushes the config and layers of the image manifest with
+// digest which aren't in dockerrepo yet, and then the manifest under reference.
+// The media type of the manifest defaults to the one it declares.
+func importManifest(dir, dockerrepo string, image imageReference, digest, reference, mediaType string, target importTarget) error {
+	content, err := ioutil.ReadFile(importBlobPath(dir, digest))
+	if err != nil {
+		return errorutils.CheckError(err)
+	}
+	manifest := imageManifest{}
+	if err := json.Unmarshal(content, &manifest); err != nil {
+		return errorutils.CheckError(err)
+	}
+	for _, blob := range append([]ociDescriptor{manifest.Config}, manifest.Layers...) {
+		exists, err := target.hasBlob(dockerrepo, image.repository, blob.Digest)
+		if err != nil {
+			return err
+		}
+		if exists {
+			log.Debug("Blob " + blob.Digest + " of " + image.name() + " is already in " + dockerrepo + ".")
+			continue
+		}
+		if err := target.uploadBlob(dockerrepo, image.repository, blob.Digest, importBlobPath(dir, blob.Digest)); err != nil {
+			return err
+		}
+	}
+	if mediaType == "" {
+		mediaType = manifest.MediaType
+	}
+	return target.putManifest(dockerrepo, image.repository, reference, mediaType, content)
+}
+
+func importBlobPath(dir, digest string) string {
+	return filepath.Join(dir, filepath.FromSlash(ociBlobPath(digest)))
+}
+
+func (at *artifactoryImportTarget) dockerUrl(repo, image, path string) string {
+	return urlAppend(at.rtDetails.Url, "api/docker/"+repo+"/v2/"+image+"/"+path)
+}
+
+func (at *artifactoryImportTarget) uploadChart(repo, name, localPath string) error {
+	return uploadFileToArtifactory(at.rtDetails, localPath, repo+"/"+name)
+}
+
+func (at *artifactoryImportTarget) hasBlob(repo, image, digest string) (bool, error) {
+	httpClientDetails := at.auth.CreateHttpClientDetails()
+	resp, _, err := at.client.SendHead(at.dockerUrl(repo, image, "blobs/"+digest), &httpClientDetails)
+	if err != nil {
+		return false, err
+	}
+	return resp.StatusCode == http.StatusOK, nil
+}
+
+// uploadBlob starts an upload session and completes it with the whole blob.
+func (at *artifactoryImportTarget) uploadBlob(repo, image, digest, localPath string) error {
+	httpClientDetails := at.auth.CreateHttpClientDetails()
+	uploadUrl := at.dockerUrl(repo, image, "blobs/uploads/")
+	resp, body, err := at.client.SendPost(uploadUrl, nil, &httpClientDetails)
+	if err != nil {
+		return err
+	}
+	if err := checkImportResponse(resp, body, http.StatusAccepted); err != nil {
+		return err
+	}
+	base, err := url.Parse(uploadUrl)
+	if err != nil {
+		return errorutils.CheckError(err)
+	}
+	location, err := base.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		return errorutils.CheckError(err)
+	}
+	query := location.Query()
+	query.Set("digest", digest)
+	location.RawQuery = query.Encode()
+	httpClientDetails = at.auth.CreateHttpClientDetails()
+	artifactoryUtils.SetContentType("application/octet-stream", &httpClientDetails.Headers)
+	resp, body, err = at.client.UploadFile(localPath, location.String(), "", &httpClientDetails, 3, nil)
+	if err != nil {
+		return err
+	}
+	return checkImportResponse(resp, body, http.StatusCreated)
+}
+
+func (at *artifactoryImportTarget) putManifest(repo, image, reference, mediaType string, content []byte) error {
+	httpClientDetails := at.auth.CreateHttpClientDetails()
+	artifactoryUtils.SetContentType(mediaType, &httpClientDetails.Headers)
+	resp, body, err := at.client.SendPut(at.dockerUrl(repo, image, "manifests/"+reference), content, &httpClientDetails)
+	if err != nil {
+		return err
+	}
+	return checkImportResponse(resp, body, http.StatusCreated)
+}
+
+func checkImportResponse(resp *http.Response, body []byte, expected ...int) error {
+	for _, status := range expected {
+		if resp.StatusCode == status {
+			return nil
+		}
+	}
+	return errorutils.CheckError(errors.New("Artifactory response: " + resp.Status + "\n" + clientutils.IndentJson(body)))
+}
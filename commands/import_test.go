@@ -0,0 +1,274 @@
+package commands
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	rtutils "github.com/jfrog/jfrog-cli-core/artifactory/utils"
+	distributionServicesUtils "github.com/jfrog/jfrog-client-go/distribution/services/utils"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fakeImportTarget records what is imported, with blobs already in the target
+// given up front.
+type fakeImportTarget struct {
+	existingBlobs map[string]bool
+	calls         []string
+	manifests     map[string][]byte
+}
+
+func (ft *fakeImportTarget) uploadChart(repo, name, localPath string) error {
+	ft.calls = append(ft.calls, "chart "+repo+"/"+name)
+	return nil
+}
+
+func (ft *fakeImportTarget) hasBlob(repo, image, digest string) (bool, error) {
+	return ft.existingBlobs[digest], nil
+}
+
+func (ft *fakeImportTarget) uploadBlob(repo, image, digest, localPath string) error {
+	content, err := ioutil.ReadFile(localPath)
+	if err != nil {
+		return err
+	}
+	if sha256Digest(content) != digest {
+		return os.ErrInvalid
+	}
+	ft.calls = append(ft.calls, "blob "+repo+"/"+image+"@"+digest)
+	ft.existingBlobs[digest] = true
+	return nil
+}
+
+func (ft *fakeImportTarget) putManifest(repo, image, reference, mediaType string, content []byte) error {
+	ft.calls = append(ft.calls, "manifest "+repo+"/"+image+":"+reference+" "+mediaType)
+	ft.manifests[image+":"+reference] = content
+	return nil
+}
+
+// exportTestArchive exports a chart and two images sharing a layer to a
+// compressed archive, and returns its path.
+func exportTestArchive(t *testing.T, metadata *bundleMetadata) string {
+	chartArchive, err := ioutil.ReadFile("testdata/artifactory-jcr-2.2.0.tgz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string][]byte{"helm-local/artifactory-jcr-2.2.0.tgz": chartArchive}
+	for p, content := range imageFiles(t, "docker-local/jfrog/artifactory-jcr/7.4.1", []byte("jcr config"), []byte("shared layer")) {
+		files[p] = content
+	}
+	for p, content := range imageFiles(t, "docker-local/library/alpine/3.10", []byte("alpine config"), []byte("shared layer")) {
+		files[p] = content
+	}
+	read, paths := writeArtifactoryFiles(t, files)
+	artifacts := imageAndChartArtifacts(map[string]string{
+		"docker.bintray.io/jfrog/artifactory-jcr:7.4.1": "docker.bintray.io/jfrog/artifactory-jcr:7.4.1",
+		"alpine:3.10": "alpine:3.10",
+	}, map[string]string{"helm-local/artifactory-jcr-2.2.0.tgz": "artifactory-jcr-2.2.0.tgz"}, "docker-local")
+	dir, err := ioutil.TempDir("", "import")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	archivePath := filepath.Join(dir, "export.tgz")
+	var out bytes.Buffer
+	gz := gzip.NewWriter(&out)
+	if _, _, err := exportArtifacts(gz, artifacts, paths, read, chartReader(read), metadata); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(archivePath, out.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return archivePath
+}
+
+func extractTestArchive(t *testing.T, archivePath string) string {
+	dir, err := ioutil.TempDir("", "import")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	if err := extractArchive(archivePath, dir); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestImportArchive(t *testing.T) {
+	metadata := &bundleMetadata{
+		Name:         "platform",
+		Version:      "1.4",
+		Description:  "The platform",
+		ReleaseNotes: &distributionServicesUtils.ReleaseNotes{Syntax: distributionServicesUtils.Markdown, Content: "# Platform"},
+	}
+	dir := extractTestArchive(t, exportTestArchive(t, metadata))
+	if err := verifyChecksums(dir); err != nil {
+		t.Fatal(err)
+	}
+	imported, err := readBundleMetadata(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(imported, metadata) {
+		t.Errorf("Expected bundle metadata %v, got %v", metadata, imported)
+	}
+
+	target := &fakeImportTarget{existingBlobs: map[string]bool{sha256Digest([]byte("alpine config")): true}, manifests: map[string][]byte{}}
+	artifacts, err := importArchive(dir, "helm-edge", "docker-edge", target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shared, jcrConfig := sha256Digest([]byte("shared layer")), sha256Digest([]byte("jcr config"))
+	expected := []string{
+		"chart helm-edge/artifactory-jcr-2.2.0.tgz",
+		"blob docker-edge/alpine@" + shared,
+		"manifest docker-edge/alpine:3.10 application/vnd.docker.distribution.manifest.v2+json",
+		"blob docker-edge/jfrog/artifactory-jcr@" + jcrConfig,
+		"manifest docker-edge/jfrog/artifactory-jcr:7.4.1 application/vnd.docker.distribution.manifest.v2+json",
+	}
+	if !reflect.DeepEqual(target.calls, expected) {
+		t.Errorf("Expected calls:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(target.calls, "\n"))
+	}
+	if len(target.manifests) != 2 {
+		t.Errorf("Expected 2 manifests, got %d", len(target.manifests))
+	}
+
+	specfiles := serializeSpecFiles(createSpecFiles(artifacts))
+	expectedSpec := `{"files":[` +
+		`{"pattern":"helm-edge/artifactory-jcr-2.2.0.tgz"},{"pattern":"helm-edge/*/artifactory-jcr-2.2.0.tgz"},` +
+		`{"pattern":"docker-edge/alpine/3.10/"},{"pattern":"docker-edge/*/alpine/3.10/"},` +
+		`{"pattern":"docker-edge/jfrog/artifactory-jcr/7.4.1/"},{"pattern":"docker-edge/*/jfrog/artifactory-jcr/7.4.1/"}]}`
+	if specfiles != expectedSpec {
+		t.Errorf("Expected spec:\n%s\ngot:\n%s", expectedSpec, specfiles)
+	}
+
+	params := applyBundleMetadata(distributionServicesUtils.NewReleaseBundleParams("", ""), imported)
+	if params.Name != "platform" || params.Version != "1.4" || params.Description != "The platform" ||
+		params.ReleaseNotes != "# Platform" || params.ReleaseNotesSyntax != distributionServicesUtils.Markdown {
+		t.Errorf("Unexpected release bundle params %+v", params)
+	}
+}
+
+func TestImportManifestList(t *testing.T) {
+	files, _ := multiArchImageFiles(t, "docker-local/library/busybox/1.31", map[string][]byte{
+		"linux/amd64":    []byte("amd64 config"),
+		"linux/arm64/v8": []byte("arm64 config"),
+	})
+	list := manifestList{}
+	if err := json.Unmarshal(files["docker-local/library/busybox/1.31/"+dockerManifestListFile], &list); err != nil {
+		t.Fatal(err)
+	}
+	read, paths := writeArtifactoryFiles(t, files)
+	results := make([]rtutils.SearchResult, 0)
+	for _, p := range paths {
+		results = append(results, rtutils.SearchResult{Path: p})
+	}
+	artifacts := imageAndChartArtifacts(map[string]string{"busybox:1.31": "busybox:1.31"}, map[string]string{}, "docker-local")
+	artifacts, _, err := expandManifestLists(artifacts, results, read, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	if _, _, err := exportArtifacts(&out, artifacts, paths, read, chartReader(read), nil); err != nil {
+		t.Fatal(err)
+	}
+	archiveDir, err := ioutil.TempDir("", "import")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(archiveDir)
+	archivePath := filepath.Join(archiveDir, "export.tar")
+	if err := ioutil.WriteFile(archivePath, out.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	dir := extractTestArchive(t, archivePath)
+
+	target := &fakeImportTarget{existingBlobs: map[string]bool{}, manifests: map[string][]byte{}}
+	imported, err := importArchive(dir, "helm-edge", "docker-edge", target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The image of each platform is pushed by digest before the list is tagged.
+	expected := make([]string, 0)
+	for i, platform := range []string{"linux/amd64", "linux/arm64/v8"} {
+		expected = append(expected,
+			"blob docker-edge/busybox@"+sha256Digest([]byte(strings.Split(platform, "/")[1]+" config")),
+			"blob docker-edge/busybox@"+sha256Digest([]byte(platform+" layer")),
+			"manifest docker-edge/busybox:"+list.Manifests[i].Digest+" application/vnd.docker.distribution.manifest.v2+json")
+	}
+	expected = append(expected, "manifest docker-edge/busybox:1.31 application/vnd.docker.distribution.manifest.list.v2+json")
+	if !reflect.DeepEqual(target.calls, expected) {
+		t.Errorf("Expected calls:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(target.calls, "\n"))
+	}
+	if !bytes.Equal(target.manifests["busybox:1.31"], files["docker-local/library/busybox/1.31/"+dockerManifestListFile]) {
+		t.Error("Expected the manifest list to be pushed as it was exported")
+	}
+	if len(imported) != 1 || imported[0].name != "busybox:1.31" {
+		t.Errorf("Unexpected imported artifacts %+v", imported)
+	}
+}
+
+func TestVerifyChecksums(t *testing.T) {
+	archivePath := exportTestArchive(t, nil)
+
+	dir := extractTestArchive(t, archivePath)
+	if metadata, err := readBundleMetadata(dir); err != nil || metadata != nil {
+		t.Errorf("Expected no bundle metadata, got %v, %v", metadata, err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "helm", "artifactory-jcr-2.2.0.tgz"), []byte("tampered"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := verifyChecksums(dir); err == nil || !strings.Contains(err.Error(), "checksum of helm/artifactory-jcr-2.2.0.tgz") {
+		t.Errorf("Expected a checksum mismatch, got %v", err)
+	}
+
+	dir = extractTestArchive(t, archivePath)
+	if err := os.Remove(filepath.Join(dir, "helm", "index.yaml")); err != nil {
+		t.Fatal(err)
+	}
+	if err := verifyChecksums(dir); err == nil || !strings.Contains(err.Error(), "missing from the archive: helm/index.yaml") {
+		t.Errorf("Expected a missing file, got %v", err)
+	}
+
+	dir = extractTestArchive(t, archivePath)
+	if err := ioutil.WriteFile(filepath.Join(dir, "extra.txt"), []byte("extra"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := verifyChecksums(dir); err == nil || !strings.Contains(err.Error(), "extra.txt isn't listed") {
+		t.Errorf("Expected an unlisted file, got %v", err)
+	}
+}
+
+func TestExtractArchiveOutsideDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "import")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	var out bytes.Buffer
+	tw := tar.NewWriter(&out)
+	if err := tw.WriteHeader(&tar.Header{Name: "../evil", Mode: 0644, Size: 4, Typeflag: tar.TypeReg}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tw.Write([]byte("evil")); err != nil {
+		t.Fatal(err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	archivePath := filepath.Join(dir, "evil.tar")
+	if err := ioutil.WriteFile(archivePath, out.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := extractArchive(archivePath, filepath.Join(dir, "extracted")); err == nil {
+		t.Error("Expected extracting a file outside of the directory to fail")
+	}
+}
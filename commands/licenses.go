@@ -0,0 +1,110 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+	"regexp"
+	"strings"
+)
+
+// licenseAnnotations are the Chart.yaml annotations declaring the license of a
+// chart, by order of precedence.
+var licenseAnnotations = []string{"licenses", "artifacthub.io/license", "license"}
+
+var licenseFiles = []string{"LICENSE", "LICENSE.md", "LICENSE.txt", "COPYING"}
+
+// licenseTexts identifies the license of a LICENSE file which doesn't give its
+// SPDX identifier, from phrases of the license text.
+var licenseTexts = []struct {
+	id      string
+	phrases []string
+}{
+	{id: "Apache-2.0", phrases: []string{"Apache License", "Version 2.0"}},
+	{id: "MIT", phrases: []string{"Permission is hereby granted, free of charge"}},
+	{id: "GPL-3.0", phrases: []string{"GNU GENERAL PUBLIC LICENSE", "Version 3"}},
+	{id: "GPL-2.0", phrases: []string{"GNU GENERAL PUBLIC LICENSE", "Version 2"}},
+	{id: "MPL-2.0", phrases: []string{"Mozilla Public License", "Version 2.0"}},
+	{id: "BSD-3-Clause", phrases: []string{"Redistribution and use in source and binary forms", "Neither the name"}},
+	{id: "BSD-2-Clause", phrases: []string{"Redistribution and use in source and binary forms"}},
+}
+
+var spdxIdentifier = regexp.MustCompile(`SPDX-License-Identifier:\s*(.+)`)
+
+// chartLicense returns the license chrt declares, as an SPDX expression, from
+// the annotations of its Chart.yaml or else from its LICENSE file, or an empty
+// string if it doesn't declare any.
+func chartLicense(chrt *chart.Chart) string {
+	for _, annotation := range licenseAnnotations {
+		if license := strings.TrimSpace(chrt.Metadata.GetAnnotations()[annotation]); license != "" {
+			return license
+		}
+	}
+	for _, file := range chrt.Files {
+		if containsString(licenseFiles, file.TypeUrl) {
+			return identifyLicense(string(file.Value))
+		}
+	}
+	return ""
+}
+
+func identifyLicense(text string) string {
+	if match := spdxIdentifier.FindStringSubmatch(text); match != nil {
+		return strings.TrimSpace(match[1])
+	}
+	for _, license := range licenseTexts {
+		found := true
+		for _, phrase := range license.phrases {
+			found = found && strings.Contains(text, phrase)
+		}
+		if found {
+			return license.id
+		}
+	}
+	return "unknown"
+}
+
+// licenseIds splits an SPDX expression, such as Apache-2.0 OR MIT or a comma
+// separated list, into the licenses it names.
+func licenseIds(license string) []string {
+	ids := make([]string, 0)
+	for _, field := range strings.FieldsFunc(license, func(r rune) bool { return r == ',' || r == ' ' || r == '(' || r == ')' }) {
+		if field != "OR" && field != "AND" && field != "WITH" {
+			ids = append(ids, field)
+		}
+	}
+	return ids
+}
+
+// chartArchiveOrigin returns the origin of a chart archive artifact, holding
+// the metadata of its chart, if the chart was read.
+func chartArchiveOrigin(artifact bundleArtifact) (artifactOrigin, bool) {
+	if artifact.packageType != "helm" || len(artifact.origins) == 0 {
+		return artifactOrigin{}, false
+	}
+	return artifact.origins[0], true
+}
+
+// printLicenses prints the license inventory of the chart archives among
+// artifacts whose chart was read.
+func printLicenses(out io.Writer, artifacts []bundleArtifact) {
+	lines := make([]string, 0)
+	for _, artifact := range artifacts {
+		origin, ok := chartArchiveOrigin(artifact)
+		if !ok {
+			continue
+		}
+		license := origin.license
+		if license == "" {
+			license = "none"
+		}
+		lines = append(lines, artifact.name+": "+license)
+	}
+	if len(lines) == 0 {
+		return
+	}
+	fmt.Fprintln(out, "Licenses:")
+	for _, line := range lines {
+		fmt.Fprintln(out, "- "+line)
+	}
+}
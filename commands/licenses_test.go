@@ -0,0 +1,72 @@
+package commands
+
+import (
+	"k8s.io/helm/pkg/chartutil"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestChartLicense(t *testing.T) {
+	chrt, err := chartutil.Load("testdata/artifactory-jcr-2.2.0.tgz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if license := chartLicense(chrt); license != "Apache-2.0" {
+		t.Errorf("Expected the LICENSE file to be Apache-2.0, got %s", license)
+	}
+	postgresql := chrt.Dependencies[0].Dependencies[0]
+	if license := chartLicense(postgresql); license != "" {
+		t.Errorf("Expected postgresql not to declare a license, got %s", license)
+	}
+
+	annotated, err := chartutil.LoadFiles([]*chartutil.BufferedFile{
+		{Name: "Chart.yaml", Data: []byte("name: app\nversion: 1.0.0\nannotations:\n  artifacthub.io/license: Apache-2.0 OR MIT\n")},
+		{Name: "LICENSE", Data: []byte("GNU GENERAL PUBLIC LICENSE\nVersion 3")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if license := identifyLicense(string(annotated.Files[0].Value)); license != "GPL-3.0" {
+		t.Errorf("Expected the LICENSE file to be GPL-3.0, got %s", license)
+	}
+	if license := chartLicense(annotated); license != "Apache-2.0 OR MIT" {
+		t.Errorf("Expected the annotation to take precedence, got %s", license)
+	}
+	if ids := licenseIds("Apache-2.0 OR (MIT AND BSD-3-Clause), ISC"); !reflect.DeepEqual(ids, []string{"Apache-2.0", "MIT", "BSD-3-Clause", "ISC"}) {
+		t.Errorf("Unexpected license ids %v", ids)
+	}
+	if license := identifyLicense("// SPDX-License-Identifier: MPL-2.0\n"); license != "MPL-2.0" {
+		t.Errorf("Expected the SPDX identifier to be used, got %s", license)
+	}
+	if license := identifyLicense("All rights reserved."); license != "unknown" {
+		t.Errorf("Expected an unknown license, got %s", license)
+	}
+}
+
+func TestEvaluateLicensePolicy(t *testing.T) {
+	chrt, err := chartutil.Load("testdata/artifactory-jcr-2.2.0.tgz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	chrt.Dependencies[0].Metadata.Maintainers = nil
+	_, artifacts, err := createFilespec(chrt, "helm-local", "docker-local")
+	if err != nil {
+		t.Fatal(err)
+	}
+	policy := &bundlePolicy{Rules: []policyRule{
+		{Rule: allowedLicensesRule, Values: []string{"Apache-2.0", "MIT"}, Severity: policyError},
+		{Rule: requiredMaintainersRule, Severity: policyWarning},
+	}}
+	actual := make([]string, 0)
+	for _, violation := range policy.evaluate(artifacts) {
+		actual = append(actual, violation.severity+" "+violation.artifact+": "+violation.message)
+	}
+	expected := []string{
+		"warning artifactory-9.4.0.tgz: the chart has no maintainers",
+		"error postgresql-8.7.3.tgz: the chart declares no license",
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected violations:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(actual, "\n"))
+	}
+}
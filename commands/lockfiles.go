@@ -0,0 +1,412 @@
+package commands
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"github.com/BurntSushi/toml"
+	"github.com/jfrog/jfrog-client-go/utils/errorutils"
+	"github.com/jfrog/jfrog-client-go/utils/log"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// lockfileFormat describes a file that pins the packages of a project. The
+// package type selects the Artifactory repository the packages are stored in.
+type lockfileFormat struct {
+	packageType string
+	parse       func(content []byte, repo string) ([]bundleArtifact, error)
+}
+
+var lockfileFormats = map[string]lockfileFormat{
+	"npm":    {packageType: "npm", parse: parseNpmLockfile},
+	"maven":  {packageType: "maven", parse: parseMavenDependencies},
+	"go":     {packageType: "go", parse: parseGoSum},
+	"pip":    {packageType: "pypi", parse: parsePipRequirements},
+	"poetry": {packageType: "pypi", parse: parsePoetryLockfile},
+}
+
+var (
+	pipRequirement    = regexp.MustCompile(`^([A-Za-z0-9][A-Za-z0-9._\-]*)(\[[^\]]*\])?\s*==\s*([^\s;,]+)`)
+	pipInclude        = regexp.MustCompile(`^(-r|--requirement|-c|--constraint)(?:\s*=\s*|\s+|)(\S+)`)
+	pythonNameDivider = regexp.MustCompile(`[-_.]+`)
+	mavenProperty     = regexp.MustCompile(`\$\{([^}]+)\}`)
+)
+
+// detectLockfileFormat picks the format of a lockfile by its name. The format
+// can also be given explicitly, as in "maven:dependency-tree.txt".
+func detectLockfileFormat(path string) (lockfileFormat, string, error) {
+	if splits := strings.SplitN(path, ":", 2); len(splits) == 2 {
+		if format, ok := lockfileFormats[splits[0]]; ok {
+			return format, splits[1], nil
+		}
+	}
+	name := strings.ToLower(filepath.Base(path))
+	switch {
+	case name == "package-lock.json" || name == "npm-shrinkwrap.json":
+		return lockfileFormats["npm"], path, nil
+	case name == "pom.xml" || strings.HasSuffix(name, ".pom"):
+		return lockfileFormats["maven"], path, nil
+	case name == "go.sum":
+		return lockfileFormats["go"], path, nil
+	case strings.HasPrefix(name, "requirements") && strings.HasSuffix(name, ".txt"):
+		return lockfileFormats["pip"], path, nil
+	case name == "poetry.lock":
+		return lockfileFormats["poetry"], path, nil
+	}
+	return lockfileFormat{}, path, errorutils.CheckError(errors.New("Cannot tell the format of " + path + ". Prefix it with one of npm:, maven:, go:, pip: or poetry:."))
+}
+
+type npmLockfile struct {
+	Packages     map[string]npmLockedPackage `json:"packages"`
+	Dependencies map[string]npmLockedPackage `json:"dependencies"`
+}
+
+type npmLockedPackage struct {
+	Name         string                      `json:"name"`
+	Version      string                      `json:"version"`
+	Link         bool                        `json:"link"`
+	Bundled      bool                        `json:"bundled"`
+	InBundle     bool                        `json:"inBundle"`
+	Dev          bool                        `json:"dev"`
+	Dependencies map[string]npmLockedPackage `json:"dependencies"`
+}
+
+// parseNpmLockfile reads a package-lock.json. Lockfile version 2 and later list
+// every package under "packages", while version 1 nests them in "dependencies".
+// Packages only the development dependencies require are left out.
+func parseNpmLockfile(content []byte, repo string) ([]bundleArtifact, error) {
+	lockfile := new(npmLockfile)
+	if err := json.Unmarshal(content, lockfile); err != nil {
+		return nil, errorutils.CheckError(err)
+	}
+	artifacts := map[string]bundleArtifact{}
+	if len(lockfile.Packages) > 0 {
+		for path, pkg := range lockfile.Packages {
+			index := strings.LastIndex(path, "node_modules/")
+			if index < 0 || pkg.Link || pkg.InBundle || pkg.Dev {
+				continue
+			}
+			name := pkg.Name
+			if name == "" {
+				name = path[index+len("node_modules/"):]
+			}
+			addNpmPackage(artifacts, repo, name, pkg.Version)
+		}
+	} else {
+		crawlNpmDependencies(artifacts, repo, lockfile.Dependencies)
+	}
+	return sortArtifactMap(artifacts), nil
+}
+
+func crawlNpmDependencies(artifacts map[string]bundleArtifact, repo string, deps map[string]npmLockedPackage) {
+	for name, pkg := range deps {
+		if pkg.Dev {
+			continue
+		}
+		if !pkg.Bundled {
+			addNpmPackage(artifacts, repo, name, pkg.Version)
+		}
+		crawlNpmDependencies(artifacts, repo, pkg.Dependencies)
+	}
+}
+
+func addNpmPackage(artifacts map[string]bundleArtifact, repo, name, version string) {
+	// Aliased packages are locked as "npm:<real name>@<version>".
+	if strings.HasPrefix(version, "npm:") {
+		alias := strings.TrimPrefix(version, "npm:")
+		index := strings.LastIndex(alias, "@")
+		if index <= 0 {
+			return
+		}
+		name, version = alias[:index], alias[index+1:]
+	}
+	if version == "" || strings.Contains(version, ":") || strings.Contains(version, "/") {
+		log.Warn("Skipping npm package " + name + ", which is not installed from a registry: " + version)
+		return
+	}
+	base := name[strings.LastIndex(name, "/")+1:]
+	// Scoped packages are stored either as @scope/name/-/name-1.0.0.tgz or as @scope/name/-/@scope/name-1.0.0.tgz.
+	artifacts[name+"@"+version] = bundleArtifact{name: name + "@" + version, patterns: []string{repo + "/" + name + "/-/*" + base + "-" + version + ".tgz"}}
+}
+
+type mavenPom struct {
+	GroupId              string            `xml:"groupId"`
+	ArtifactId           string            `xml:"artifactId"`
+	Version              string            `xml:"version"`
+	Parent               mavenCoordinate   `xml:"parent"`
+	Properties           mavenProperties   `xml:"properties"`
+	Dependencies         []mavenCoordinate `xml:"dependencies>dependency"`
+	DependencyManagement []mavenCoordinate `xml:"dependencyManagement>dependencies>dependency"`
+}
+
+type mavenProperties struct {
+	Entries []struct {
+		XMLName xml.Name
+		Value   string `xml:",chardata"`
+	} `xml:",any"`
+}
+
+type mavenCoordinate struct {
+	GroupId    string `xml:"groupId"`
+	ArtifactId string `xml:"artifactId"`
+	Version    string `xml:"version"`
+	Type       string `xml:"type"`
+	Classifier string `xml:"classifier"`
+	Scope      string `xml:"scope"`
+}
+
+// parseMavenDependencies reads either the dependencies declared in a pom.xml,
+// or a resolved dependency list, as written by
+// "mvn dependency:tree -DoutputFile=<file>" or "mvn dependency:list -DoutputFile=<file>".
+func parseMavenDependencies(content []byte, repo string) ([]bundleArtifact, error) {
+	var deps []mavenCoordinate
+	var err error
+	if bytes.HasPrefix(bytes.TrimSpace(content), []byte("<")) {
+		deps, err = parseMavenPom(content)
+	} else {
+		deps, err = parseMavenDependencyList(content)
+	}
+	if err != nil {
+		return nil, err
+	}
+	artifacts := map[string]bundleArtifact{}
+	for _, dep := range deps {
+		if dep.Scope == "test" || dep.Scope == "provided" || dep.Scope == "system" {
+			continue
+		}
+		folder := strings.ReplaceAll(dep.GroupId, ".", "/") + "/" + dep.ArtifactId + "/" + dep.Version + "/"
+		base := dep.ArtifactId + "-" + dep.Version
+		coordinate := dep.GroupId + ":" + dep.ArtifactId + ":" + dep.Version
+		artifacts[coordinate+" (pom)"] = bundleArtifact{name: coordinate + " (pom)", patterns: []string{repo + "/" + folder + base + ".pom"}}
+		if dep.Type == "pom" {
+			continue
+		}
+		file := base
+		if dep.Classifier != "" {
+			file = file + "-" + dep.Classifier
+			coordinate = coordinate + ":" + dep.Classifier
+		}
+		artifacts[coordinate] = bundleArtifact{name: coordinate, patterns: []string{repo + "/" + folder + file + "." + mavenExtension(dep.Type)}}
+	}
+	return sortArtifactMap(artifacts), nil
+}
+
+func parseMavenPom(content []byte) ([]mavenCoordinate, error) {
+	pom := new(mavenPom)
+	if err := xml.Unmarshal(content, pom); err != nil {
+		return nil, errorutils.CheckError(err)
+	}
+	props := map[string]string{
+		"project.groupId":        pom.GroupId,
+		"project.artifactId":     pom.ArtifactId,
+		"project.version":        pom.Version,
+		"project.parent.groupId": pom.Parent.GroupId,
+		"project.parent.version": pom.Parent.Version,
+	}
+	if pom.GroupId == "" {
+		props["project.groupId"] = pom.Parent.GroupId
+	}
+	if pom.Version == "" {
+		props["project.version"] = pom.Parent.Version
+	}
+	for _, entry := range pom.Properties.Entries {
+		props[entry.XMLName.Local] = strings.TrimSpace(entry.Value)
+	}
+	interpolate := func(value string) string {
+		return mavenProperty.ReplaceAllStringFunc(value, func(ref string) string {
+			if resolved, ok := props[ref[2:len(ref)-1]]; ok {
+				return resolved
+			}
+			return ref
+		})
+	}
+	managed := map[string]string{}
+	for _, dep := range pom.DependencyManagement {
+		managed[interpolate(dep.GroupId)+":"+interpolate(dep.ArtifactId)] = interpolate(dep.Version)
+	}
+	deps := make([]mavenCoordinate, 0, len(pom.Dependencies))
+	for _, dep := range pom.Dependencies {
+		dep.GroupId = interpolate(dep.GroupId)
+		dep.ArtifactId = interpolate(dep.ArtifactId)
+		dep.Version = interpolate(dep.Version)
+		dep.Classifier = interpolate(dep.Classifier)
+		if dep.Version == "" {
+			dep.Version = managed[dep.GroupId+":"+dep.ArtifactId]
+		}
+		if dep.Version == "" || strings.Contains(dep.Version, "${") {
+			log.Warn("Skipping Maven dependency " + dep.GroupId + ":" + dep.ArtifactId + ", whose version is not set in the pom.")
+			continue
+		}
+		deps = append(deps, dep)
+	}
+	return deps, nil
+}
+
+// parseMavenDependencyList reads coordinates such as
+// "+- org.slf4j:slf4j-api:jar:1.7.30:compile". The first coordinate of a
+// dependency tree, which has no scope, is the project itself.
+func parseMavenDependencyList(content []byte) ([]mavenCoordinate, error) {
+	deps := make([]mavenCoordinate, 0)
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimLeftFunc(strings.TrimPrefix(scanner.Text(), "[INFO]"), func(r rune) bool {
+			return unicode.IsSpace(r) || strings.ContainsRune("+-|\\", r)
+		})
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		parts := strings.Split(fields[0], ":")
+		switch len(parts) {
+		case 5:
+			deps = append(deps, mavenCoordinate{GroupId: parts[0], ArtifactId: parts[1], Type: parts[2], Version: parts[3], Scope: parts[4]})
+		case 6:
+			deps = append(deps, mavenCoordinate{GroupId: parts[0], ArtifactId: parts[1], Type: parts[2], Classifier: parts[3], Version: parts[4], Scope: parts[5]})
+		}
+	}
+	return deps, errorutils.CheckError(scanner.Err())
+}
+
+func mavenExtension(packaging string) string {
+	switch packaging {
+	case "", "jar", "bundle", "maven-plugin", "ejb", "test-jar":
+		return "jar"
+	}
+	return packaging
+}
+
+// parseGoSum reads a go.sum file. Modules which are only listed with their
+// go.mod hash are needed for version selection, but their source is not.
+func parseGoSum(content []byte, repo string) ([]bundleArtifact, error) {
+	artifacts := map[string]bundleArtifact{}
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 3 {
+			continue
+		}
+		module, version := fields[0], fields[1]
+		folder := repo + "/" + escapeGoModulePath(module) + "/@v/"
+		if strings.HasSuffix(version, "/go.mod") {
+			version = strings.TrimSuffix(version, "/go.mod")
+			name := module + "@" + version + " (go.mod)"
+			artifacts[name] = bundleArtifact{name: name, patterns: []string{folder + version + ".mod"}}
+		} else {
+			name := module + "@" + version
+			artifacts[name] = bundleArtifact{name: name, patterns: []string{folder + version + ".zip"}}
+		}
+	}
+	return sortArtifactMap(artifacts), errorutils.CheckError(scanner.Err())
+}
+
+// escapeGoModulePath escapes upper case letters the way module proxies do,
+// so that github.com/Azure becomes github.com/!azure.
+func escapeGoModulePath(path string) string {
+	escaped := strings.Builder{}
+	for _, r := range path {
+		if unicode.IsUpper(r) {
+			escaped.WriteRune('!')
+			r = unicode.ToLower(r)
+		}
+		escaped.WriteRune(r)
+	}
+	return escaped.String()
+}
+
+// parsePipRequirements reads the packages pinned with "==" in a requirements
+// file. Requirements with any other specifier don't name a single version.
+func parsePipRequirements(content []byte, repo string) ([]bundleArtifact, error) {
+	artifacts := map[string]bundleArtifact{}
+	text := strings.ReplaceAll(string(content), "\\\n", " ")
+	for _, line := range strings.Split(text, "\n") {
+		if index := strings.Index(line, " #"); index >= 0 {
+			line = line[:index]
+		}
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "-") {
+			// Included files are named relative to the requirements file,
+			// whose path isn't known here.
+			if match := pipInclude.FindStringSubmatch(line); match != nil {
+				log.Warn("Skipping the requirements of " + match[2] + ", included with " + match[1] + ", which are not read.")
+			}
+			continue
+		}
+		match := pipRequirement.FindStringSubmatch(line)
+		if match == nil {
+			log.Warn("Skipping requirement " + line + ", which is not pinned to a version.")
+			continue
+		}
+		artifact := newPythonArtifact(repo, match[1], match[3])
+		artifacts[artifact.name] = artifact
+	}
+	return sortArtifactMap(artifacts), nil
+}
+
+type poetryLockfile struct {
+	Package []struct {
+		Name     string `toml:"name"`
+		Version  string `toml:"version"`
+		Category string `toml:"category"`
+		Source   struct {
+			Type string `toml:"type"`
+		} `toml:"source"`
+	} `toml:"package"`
+}
+
+func parsePoetryLockfile(content []byte, repo string) ([]bundleArtifact, error) {
+	lockfile := new(poetryLockfile)
+	if _, err := toml.Decode(string(content), lockfile); err != nil {
+		return nil, errorutils.CheckError(err)
+	}
+	artifacts := map[string]bundleArtifact{}
+	for _, pkg := range lockfile.Package {
+		if pkg.Category == "dev" {
+			continue
+		}
+		if pkg.Source.Type != "" && pkg.Source.Type != "legacy" {
+			log.Warn("Skipping Python package " + pkg.Name + ", which is installed from a " + pkg.Source.Type + ".")
+			continue
+		}
+		artifact := newPythonArtifact(repo, pkg.Name, pkg.Version)
+		artifacts[artifact.name] = artifact
+	}
+	return sortArtifactMap(artifacts), nil
+}
+
+// newPythonArtifact matches any wheel or source distribution of a package.
+// Wheel file names replace dashes in the project name with underscores, but
+// older source distributions keep them.
+func newPythonArtifact(repo, name, version string) bundleArtifact {
+	normalized := strings.ToLower(pythonNameDivider.ReplaceAllString(name, "-"))
+	underscored := strings.ReplaceAll(normalized, "-", "_")
+	patterns := []string{
+		repo + "/*/" + underscored + "-" + version + "-*.whl",
+		repo + "/*/" + underscored + "-" + version + ".tar.gz",
+	}
+	if underscored != normalized {
+		patterns = append(patterns, repo+"/*/"+normalized+"-"+version+".tar.gz")
+	}
+	return bundleArtifact{name: normalized + "==" + version, patterns: patterns}
+}
+
+func sortArtifactMap(in map[string]bundleArtifact) []bundleArtifact {
+	keys := make([]string, 0, len(in))
+	for k := range in {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	vals := make([]bundleArtifact, 0, len(in))
+	for _, k := range keys {
+		vals = append(vals, in[k])
+	}
+	return vals
+}
@@ -0,0 +1,106 @@
+package commands
+
+import (
+	"bytes"
+	"github.com/jfrog/jfrog-client-go/utils/log"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLockfiles(t *testing.T) {
+	tests := []struct {
+		path     string
+		expected []bundleArtifact
+	}{
+		{"testdata/lockfiles/package-lock.json", []bundleArtifact{
+			{name: "@babel/core@7.12.3", patterns: []string{"npmrepo/@babel/core/-/*core-7.12.3.tgz"}},
+			{name: "lodash@4.17.20", patterns: []string{"npmrepo/lodash/-/*lodash-4.17.20.tgz"}},
+			{name: "semver@5.7.1", patterns: []string{"npmrepo/semver/-/*semver-5.7.1.tgz"}},
+		}},
+		{"npm:testdata/lockfiles/package-lock-v1.json", []bundleArtifact{
+			{name: "@babel/core@7.12.3", patterns: []string{"npmrepo/@babel/core/-/*core-7.12.3.tgz"}},
+			{name: "lodash@4.17.20", patterns: []string{"npmrepo/lodash/-/*lodash-4.17.20.tgz"}},
+			{name: "semver@5.7.1", patterns: []string{"npmrepo/semver/-/*semver-5.7.1.tgz"}},
+		}},
+		{"testdata/lockfiles/pom.xml", []bundleArtifact{
+			{name: "com.acme:common:2.0.0 (pom)", patterns: []string{"mavenrepo/com/acme/common/2.0.0/common-2.0.0.pom"}},
+			{name: "com.acme:common:2.0.0:linux", patterns: []string{"mavenrepo/com/acme/common/2.0.0/common-2.0.0-linux.jar"}},
+			{name: "com.google.guava:guava:29.0-jre", patterns: []string{"mavenrepo/com/google/guava/guava/29.0-jre/guava-29.0-jre.jar"}},
+			{name: "com.google.guava:guava:29.0-jre (pom)", patterns: []string{"mavenrepo/com/google/guava/guava/29.0-jre/guava-29.0-jre.pom"}},
+			{name: "org.slf4j:slf4j-api:1.7.30", patterns: []string{"mavenrepo/org/slf4j/slf4j-api/1.7.30/slf4j-api-1.7.30.jar"}},
+			{name: "org.slf4j:slf4j-api:1.7.30 (pom)", patterns: []string{"mavenrepo/org/slf4j/slf4j-api/1.7.30/slf4j-api-1.7.30.pom"}},
+		}},
+		{"maven:testdata/lockfiles/dependency-tree.txt", []bundleArtifact{
+			{name: "com.acme:bom:2.0.0 (pom)", patterns: []string{"mavenrepo/com/acme/bom/2.0.0/bom-2.0.0.pom"}},
+			{name: "com.acme:common:2.0.0 (pom)", patterns: []string{"mavenrepo/com/acme/common/2.0.0/common-2.0.0.pom"}},
+			{name: "com.acme:common:2.0.0:linux", patterns: []string{"mavenrepo/com/acme/common/2.0.0/common-2.0.0-linux.jar"}},
+			{name: "org.slf4j:slf4j-api:1.7.30", patterns: []string{"mavenrepo/org/slf4j/slf4j-api/1.7.30/slf4j-api-1.7.30.jar"}},
+			{name: "org.slf4j:slf4j-api:1.7.30 (pom)", patterns: []string{"mavenrepo/org/slf4j/slf4j-api/1.7.30/slf4j-api-1.7.30.pom"}},
+		}},
+		{"testdata/lockfiles/go.sum", []bundleArtifact{
+			{name: "github.com/Azure/go-autorest@v14.2.0+incompatible", patterns: []string{"gorepo/github.com/!azure/go-autorest/@v/v14.2.0+incompatible.zip"}},
+			{name: "github.com/Azure/go-autorest@v14.2.0+incompatible (go.mod)", patterns: []string{"gorepo/github.com/!azure/go-autorest/@v/v14.2.0+incompatible.mod"}},
+			{name: "golang.org/x/text@v0.3.0 (go.mod)", patterns: []string{"gorepo/golang.org/x/text/@v/v0.3.0.mod"}},
+		}},
+		{"testdata/lockfiles/requirements.txt", []bundleArtifact{
+			{name: "python-dateutil==2.8.1", patterns: []string{"pypirepo/*/python_dateutil-2.8.1-*.whl", "pypirepo/*/python_dateutil-2.8.1.tar.gz", "pypirepo/*/python-dateutil-2.8.1.tar.gz"}},
+			{name: "requests==2.24.0", patterns: []string{"pypirepo/*/requests-2.24.0-*.whl", "pypirepo/*/requests-2.24.0.tar.gz"}},
+		}},
+		{"testdata/lockfiles/poetry.lock", []bundleArtifact{
+			{name: "zope-interface==5.1.2", patterns: []string{"pypirepo/*/zope_interface-5.1.2-*.whl", "pypirepo/*/zope_interface-5.1.2.tar.gz", "pypirepo/*/zope-interface-5.1.2.tar.gz"}},
+		}},
+	}
+	repos := map[string]string{"npm": "npmrepo", "maven": "mavenrepo", "go": "gorepo", "pypi": "pypirepo"}
+	for _, test := range tests {
+		format, path, err := detectLockfileFormat(test.path)
+		if err != nil {
+			t.Fatalf("Error detecting the format of %s: %s\n", test.path, err)
+		}
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatalf("Error reading %s: %s\n", path, err)
+		}
+		artifacts, err := format.parse(content, repos[format.packageType])
+		if err != nil {
+			t.Fatalf("Error parsing %s: %s\n", path, err)
+		}
+		if !reflect.DeepEqual(artifacts, test.expected) {
+			t.Fatalf("Parsed %s incorrectly. Expected:\n%v\nGot:\n%v\n", path, test.expected, artifacts)
+		}
+	}
+}
+
+func TestArtifactFoundIn(t *testing.T) {
+	artifact := bundleArtifact{name: "jfrog/artifactory-jcr:7.4.1", patterns: []string{"docker/jfrog/artifactory-jcr/7.4.1/", "docker/*/jfrog/artifactory-jcr/7.4.1/"}}
+	if !artifact.foundIn([]string{"docker/remote-cache/jfrog/artifactory-jcr/7.4.1/manifest.json"}) {
+		t.Fatalf("Expected %v to be found\n", artifact)
+	}
+	if artifact.foundIn([]string{"docker/jfrog/artifactory-jcr/7.4.10/manifest.json"}) {
+		t.Fatalf("Expected %v not to be found\n", artifact)
+	}
+}
+
+func TestPipRequirementsIncludes(t *testing.T) {
+	previous := log.Logger
+	defer log.SetLogger(previous)
+	logs := &bytes.Buffer{}
+	log.SetLogger(log.NewLogger(log.WARN, logs))
+	content := []byte("-r base.txt\n--constraint=constraints.txt\n--index-url https://pypi.example.com/simple\nsix==1.15.0\n")
+	artifacts, err := parsePipRequirements(content, "pypirepo")
+	if err != nil {
+		t.Fatalf("Error parsing requirements: %s\n", err)
+	}
+	if len(artifacts) != 1 || artifacts[0].name != "six==1.15.0" {
+		t.Fatalf("Requirements are incorrect: %+v\n", artifacts)
+	}
+	for _, warning := range []string{"Skipping the requirements of base.txt, included with -r", "Skipping the requirements of constraints.txt, included with --constraint"} {
+		if !strings.Contains(logs.String(), warning) {
+			t.Fatalf("Expected a warning containing %q, got:\n%s\n", warning, logs.String())
+		}
+	}
+	if strings.Contains(logs.String(), "index-url") {
+		t.Fatalf("Expected no warning for --index-url, got:\n%s\n", logs.String())
+	}
+}
@@ -0,0 +1,165 @@
+package commands
+
+import (
+	"errors"
+	"github.com/jfrog/jfrog-cli-core/artifactory/spec"
+	clientutils "github.com/jfrog/jfrog-client-go/utils"
+	"github.com/jfrog/jfrog-client-go/utils/errorutils"
+	"regexp"
+	"strings"
+)
+
+// repoMapping maps a source repository to the repository its artifacts are
+// stored in on the edge nodes. A mapping without a package type applies to
+// artifacts of all types.
+type repoMapping struct {
+	packageType string
+	source      string
+	target      string
+}
+
+// releaseBundleMapping is a Distribution path mapping. Paths matching the
+// input regular expression are stored at output on the edge nodes, in which $n
+// is replaced by the n-th group of input.
+type releaseBundleMapping struct {
+	Input  string `json:"input"`
+	Output string `json:"output"`
+}
+
+var placeholder = regexp.MustCompile(`\{(\d+)\}`)
+
+var outputGroup = regexp.MustCompile(`\$(\d+)`)
+
+// parseRepoMappings parses a semicolon-separated list of mappings, each given
+// as source=target, or as type:source=target to only map artifacts of a
+// package type, such as docker or helm.
+func parseRepoMappings(mappings string) ([]repoMapping, error) {
+	parsed := make([]repoMapping, 0)
+	if mappings == "" {
+		return parsed, nil
+	}
+	for _, mapping := range strings.Split(mappings, ";") {
+		mapping = strings.TrimSpace(mapping)
+		if mapping == "" {
+			continue
+		}
+		packageType := ""
+		if i := strings.Index(mapping, ":"); i >= 0 {
+			packageType, mapping = mapping[:i], mapping[i+1:]
+		}
+		splits := strings.Split(mapping, "=")
+		if len(splits) != 2 || splits[0] == "" || splits[1] == "" || strings.Contains(mapping, "/") {
+			return nil, errorutils.CheckError(errors.New("Repository mappings must be given as [type:]source=target, got " + mapping + "."))
+		}
+		parsed = append(parsed, repoMapping{packageType: packageType, source: splits[0], target: splits[1]})
+	}
+	return parsed, nil
+}
+
+// applyRepoMappings sets the target repositories of artifacts. A mapping for
+// the package type of an artifact wins over one for all types.
+func applyRepoMappings(artifacts []bundleArtifact, mappings []repoMapping) []bundleArtifact {
+	if len(mappings) == 0 {
+		return artifacts
+	}
+	mapped := make([]bundleArtifact, 0)
+	for _, artifact := range artifacts {
+		targets := map[string]string{}
+		for _, mapping := range mappings {
+			if mapping.packageType == "" && targets[mapping.source] == "" {
+				targets[mapping.source] = mapping.target
+			}
+		}
+		for _, mapping := range mappings {
+			if mapping.packageType != "" && mapping.packageType == artifact.packageType {
+				targets[mapping.source] = mapping.target
+			}
+		}
+		artifact.targetRepos = targets
+		mapped = append(mapped, artifact)
+	}
+	return mapped
+}
+
+// mapSpecFile moves file to its target repository, if any, with a target path
+// whose placeholder is the path of the file in its source repository. Files
+// with a target of their own, and AQL queries, are left as they are.
+func mapSpecFile(file spec.File, targetRepos map[string]string) spec.File {
+	if file.Pattern == "" || file.Target != "" || len(targetRepos) == 0 {
+		return file
+	}
+	repo := strings.SplitN(file.Pattern, "/", 2)[0]
+	target, ok := targetRepos[repo]
+	if !ok {
+		return file
+	}
+	file.Pattern = placeholderPattern(file.Pattern)
+	file.Target = target + "/{1}"
+	return file
+}
+
+// placeholderPattern turns the path of a pattern in its repository into a
+// placeholder. A pattern ending with a slash matches everything in that folder,
+// which the placeholder has to include.
+func placeholderPattern(pattern string) string {
+	splits := strings.SplitN(pattern, "/", 2)
+	path := ""
+	if len(splits) == 2 {
+		path = splits[1]
+	}
+	if path == "" || strings.HasSuffix(path, "/") {
+		path = path + "*"
+	}
+	return splits[0] + "/(" + path + ")"
+}
+
+// pathMappingFromSpecFile converts the pattern and target of a file spec entry
+// to a Distribution path mapping.
+func pathMappingFromSpecFile(file spec.File) *releaseBundleMapping {
+	if file.Pattern == "" || file.Target == "" {
+		return nil
+	}
+	// A target folder without placeholders gets the path of the file in its
+	// source repository, as when copying without --flat.
+	if strings.HasSuffix(file.Target, "/") && !placeholder.MatchString(file.Target) {
+		file.Pattern = placeholderPattern(file.Pattern)
+		file.Target = file.Target + "{1}"
+	}
+	parentheses := clientutils.NewParenthesesSlice(file.Pattern, file.Target)
+	input := ""
+	for i, c := range file.Pattern {
+		switch {
+		case (c == '(' || c == ')') && parentheses.IsPresent(i):
+			input = input + string(c)
+		case c == '*':
+			input = input + ".*"
+		default:
+			input = input + regexp.QuoteMeta(string(c))
+		}
+	}
+	if strings.HasSuffix(file.Pattern, "/") {
+		input = input + ".*"
+	}
+	output := placeholder.ReplaceAllString(file.Target, "$$$1")
+	return &releaseBundleMapping{Input: "^" + input + "$", Output: output}
+}
+
+// mapPath returns the path the first of mappings whose input matches path
+// stores it at on the edge nodes, or an empty string if none matches.
+func mapPath(path string, mappings []releaseBundleMapping) (string, error) {
+	for _, mapping := range mappings {
+		input, err := regexp.Compile("^(?:" + mapping.Input + ")$")
+		if err != nil {
+			return "", errorutils.CheckError(errors.New("Path mapping " + mapping.Input + " is not a valid regular expression: " + err.Error()))
+		}
+		match := input.FindStringSubmatchIndex(path)
+		if match == nil {
+			continue
+		}
+		// Groups are delimited, so that $1 followed by a digit isn't read as a
+		// group with a longer number.
+		output := outputGroup.ReplaceAllString(mapping.Output, "$${$1}")
+		return string(input.ExpandString(nil, output, path, match)), nil
+	}
+	return "", nil
+}
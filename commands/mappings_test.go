@@ -0,0 +1,70 @@
+package commands
+
+import (
+	"github.com/jfrog/jfrog-cli-core/artifactory/spec"
+	distributionServicesUtils "github.com/jfrog/jfrog-client-go/distribution/services/utils"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseRepoMappings(t *testing.T) {
+	mappings, err := parseRepoMappings("docker:docker-prod-local=docker-edge; helm-local=helm-edge;")
+	if err != nil {
+		t.Fatalf("Error parsing repository mappings: %s\n", err)
+	}
+	expected := []repoMapping{{"docker", "docker-prod-local", "docker-edge"}, {"", "helm-local", "helm-edge"}}
+	if !reflect.DeepEqual(mappings, expected) {
+		t.Fatalf("Parsed repository mappings are incorrect: %+v\n", mappings)
+	}
+	for _, invalid := range []string{"docker-prod-local", "docker:=docker-edge", "docker-prod-local/path=docker-edge"} {
+		if _, err = parseRepoMappings(invalid); err == nil {
+			t.Fatalf("Expected an error parsing %s\n", invalid)
+		}
+	}
+}
+
+func TestRepoMappings(t *testing.T) {
+	expected := "{\"files\":[{\"pattern\":\"docker-prod-local/(alpine/3.10/*)\",\"target\":\"docker-edge/{1}\"},{\"pattern\":\"docker-prod-local/(*/alpine/3.10/*)\",\"target\":\"docker-edge/{1}\"},{\"pattern\":\"helm-local/(artifactory-jcr-2.2.0.tgz)\",\"target\":\"helm-edge/{1}\"},{\"pattern\":\"helm-local/(*/artifactory-jcr-2.2.0.tgz)\",\"target\":\"helm-edge/{1}\"},{\"pattern\":\"generic-local/installers/*.zip\",\"target\":\"generic-edge/\"},{\"pattern\":\"npm-local/lodash/-/*lodash-4.17.20.tgz\"}]}"
+	artifacts := imageAndChartArtifacts(map[string]string{"alpine:3.10": "alpine:3.10"}, map[string]string{"helm-local/artifactory-jcr-2.2.0.tgz": "artifactory-jcr-2.2.0.tgz"}, "docker-prod-local")
+	artifacts = append(artifacts,
+		bundleArtifact{name: "installers", files: []spec.File{{Pattern: "generic-local/installers/*.zip", Target: "generic-edge/"}}},
+		bundleArtifact{name: "lodash@4.17.20", packageType: "npm", patterns: []string{"npm-local/lodash/-/*lodash-4.17.20.tgz"}})
+	mappings, err := parseRepoMappings("docker:docker-prod-local=docker-edge;helm-local=helm-edge;npm:helm-local=npm-edge;docker:npm-local=npm-edge")
+	if err != nil {
+		t.Fatalf("Error parsing repository mappings: %s\n", err)
+	}
+	artifacts = applyRepoMappings(artifacts, mappings)
+	specstr := serializeSpecFiles(createSpecFiles(artifacts))
+	if specstr != expected {
+		t.Fatalf("Generated spec is incorrect. Expected:\n%s\nGot:\n%s\n", expected, specstr)
+	}
+
+	body, err := createReleaseBundleBody(distributionServicesUtils.NewReleaseBundleParams("platform", "1.5"), artifacts, false)
+	if err != nil {
+		t.Fatalf("Error creating release bundle body: %s\n", err)
+	}
+	expectedMappings := []*releaseBundleMapping{
+		{Input: "^docker-prod-local/(alpine/3\\.10/.*)$", Output: "docker-edge/$1"},
+		{Input: "^docker-prod-local/(.*/alpine/3\\.10/.*)$", Output: "docker-edge/$1"},
+		{Input: "^helm-local/(artifactory-jcr-2\\.2\\.0\\.tgz)$", Output: "helm-edge/$1"},
+		{Input: "^helm-local/(.*/artifactory-jcr-2\\.2\\.0\\.tgz)$", Output: "helm-edge/$1"},
+		{Input: "^generic-local/(installers/.*\\.zip)$", Output: "generic-edge/$1"},
+		nil,
+	}
+	if len(body.Spec.Queries) != len(expectedMappings) {
+		t.Fatalf("Expected %d queries, got %d\n", len(expectedMappings), len(body.Spec.Queries))
+	}
+	if !strings.Contains(body.Spec.Queries[0].Aql, `{"repo":"docker-prod-local","path":"alpine/3.10","name":{"$match":"*"}}`) {
+		t.Fatalf("The query of a mapped pattern is incorrect: %s\n", body.Spec.Queries[0].Aql)
+	}
+	for i, query := range body.Spec.Queries {
+		if expectedMappings[i] == nil {
+			if len(query.Mappings) != 0 {
+				t.Fatalf("Query %d should have no mappings, got %+v\n", i, query.Mappings)
+			}
+		} else if len(query.Mappings) != 1 || query.Mappings[0] != *expectedMappings[i] {
+			t.Fatalf("Mappings of query %d are incorrect. Expected:\n%+v\nGot:\n%+v\n", i, *expectedMappings[i], query.Mappings)
+		}
+	}
+}
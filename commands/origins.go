@@ -0,0 +1,193 @@
+package commands
+
+import (
+	"errors"
+	"github.com/jfrog/jfrog-client-go/utils/errorutils"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+	"sort"
+	"strings"
+)
+
+const (
+	originChartProp         = "origin.chart"
+	originParentChartProp   = "origin.parent.chart"
+	originValuesProfileProp = "origin.values.profile"
+	originImageProp         = "origin.image"
+	originRegistryProp      = "origin.registry"
+	defaultValuesProfile    = "default"
+	defaultRegistry         = "docker.io"
+)
+
+// artifactOrigin is a chart an artifact is in the bundle for: the chart whose
+// templates use an image, or the chart which requires a chart archive. The
+// origin of a chart archive tells whether its Chart.yaml deprecates it, the
+// license it declares and whether it sets maintainers.
+type artifactOrigin struct {
+	chart         string
+	parentChart   string
+	valuesProfile string
+	deprecated    bool
+	license       string
+	maintained    bool
+}
+
+// originIndex lists the origins of artifacts by their source, the image
+// reference or chart archive key they're created from.
+type originIndex map[string][]artifactOrigin
+
+func (oi originIndex) add(source string, origin artifactOrigin) {
+	for _, existing := range oi[source] {
+		if existing == origin {
+			return
+		}
+	}
+	oi[source] = append(oi[source], origin)
+}
+
+// addChartOrigins adds the origins of the images used by the rendered files of
+// chrt, and of the archives of chrt and its dependencies in helmrepo.
+func (oi originIndex) addChartOrigins(chrt *chart.Chart, files map[string]string, helmrepo, valuesProfile string) {
+	if valuesProfile == "" {
+		valuesProfile = defaultValuesProfile
+	}
+	for name, content := range files {
+		charts := strings.Split(strings.SplitN(name, "/templates/", 2)[0], "/charts/")
+		origin := artifactOrigin{chart: charts[len(charts)-1], valuesProfile: valuesProfile}
+		if len(charts) > 1 {
+			origin.parentChart = charts[len(charts)-2]
+		}
+		for image := range extractImages(map[string]string{name: content}) {
+			oi.add(image, origin)
+		}
+	}
+	if helmrepo != "" {
+		oi.addArchiveOrigins(chrt, "", helmrepo, valuesProfile)
+	}
+}
+
+func (oi originIndex) addArchiveOrigins(chrt *chart.Chart, parent, helmrepo, valuesProfile string) {
+	archive := helmrepo + "/" + chrt.Metadata.Name + "-" + chrt.Metadata.Version + ".tgz"
+	oi.add(archive, artifactOrigin{chart: chrt.Metadata.Name, parentChart: parent, valuesProfile: valuesProfile,
+		deprecated: chrt.Metadata.Deprecated, license: chartLicense(chrt), maintained: len(chrt.Metadata.Maintainers) > 0})
+	for _, dep := range chrt.GetDependencies() {
+		oi.addArchiveOrigins(dep, chrt.Metadata.Name, helmrepo, valuesProfile)
+	}
+}
+
+// attachOrigins sets the origins of artifacts from the index.
+func attachOrigins(artifacts []bundleArtifact, index originIndex) []bundleArtifact {
+	attached := make([]bundleArtifact, 0)
+	for _, artifact := range artifacts {
+		artifact.origins = append(append([]artifactOrigin{}, artifact.origins...), index[artifact.source]...)
+		attached = append(attached, artifact)
+	}
+	return attached
+}
+
+// applyBundleProps adds the custom properties of options to artifacts, and
+// their origin properties if options ask for them.
+func applyBundleProps(artifacts []bundleArtifact, options bundleOptions) []bundleArtifact {
+	if !options.originProps && len(options.props) == 0 {
+		return artifacts
+	}
+	applied := make([]bundleArtifact, 0)
+	for _, artifact := range artifacts {
+		props := options.props
+		if options.originProps {
+			props = append(originProps(artifact), props...)
+		}
+		artifact.addedProps = mergeProps(artifact.addedProps, props...)
+		applied = append(applied, artifact)
+	}
+	return applied
+}
+
+// originProps returns the properties describing where an artifact comes from.
+func originProps(artifact bundleArtifact) []releaseBundleProp {
+	props := make([]releaseBundleProp, 0)
+	if artifact.packageType == "docker" && artifact.source != "" {
+		registry := parseImageReference(artifact.source).registry
+		if registry == "" {
+			registry = defaultRegistry
+		}
+		props = append(props, releaseBundleProp{Key: originImageProp, Values: []string{artifact.source}},
+			releaseBundleProp{Key: originRegistryProp, Values: []string{registry}})
+	}
+	charts, parents, profiles := map[string]bool{}, map[string]bool{}, map[string]bool{}
+	for _, origin := range artifact.origins {
+		charts[origin.chart] = true
+		if origin.parentChart != "" {
+			parents[origin.parentChart] = true
+		}
+		profiles[origin.valuesProfile] = true
+	}
+	for _, prop := range []struct {
+		key    string
+		values map[string]bool
+	}{{originChartProp, charts}, {originParentChartProp, parents}, {originValuesProfileProp, profiles}} {
+		if len(prop.values) > 0 {
+			props = append(props, releaseBundleProp{Key: prop.key, Values: sortedSet(prop.values)})
+		}
+	}
+	return props
+}
+
+// parseProps parses properties given as "key1=value1;key2=value2,value3".
+func parseProps(props string) ([]releaseBundleProp, error) {
+	parsed := make([]releaseBundleProp, 0)
+	for _, prop := range strings.Split(props, ";") {
+		if strings.TrimSpace(prop) == "" {
+			continue
+		}
+		splits := strings.SplitN(prop, "=", 2)
+		key := strings.TrimSpace(splits[0])
+		if len(splits) != 2 || key == "" {
+			return nil, errorutils.CheckError(errors.New("Properties must be given as key1=value1;key2=value2,value3, got " + prop + "."))
+		}
+		parsed = append(parsed, releaseBundleProp{Key: key, Values: strings.Split(splits[1], ",")})
+	}
+	return parsed, nil
+}
+
+// mergeProps adds props to existing ones, adding values to existing keys.
+func mergeProps(existing []releaseBundleProp, props ...releaseBundleProp) []releaseBundleProp {
+	merged := make([]releaseBundleProp, 0)
+	for _, prop := range existing {
+		merged = append(merged, releaseBundleProp{Key: prop.Key, Values: append([]string{}, prop.Values...)})
+	}
+	for _, prop := range props {
+		found := false
+		for i := range merged {
+			if merged[i].Key == prop.Key {
+				found = true
+				for _, value := range prop.Values {
+					if !containsString(merged[i].Values, value) {
+						merged[i].Values = append(merged[i].Values, value)
+					}
+				}
+			}
+		}
+		if !found {
+			merged = append(merged, releaseBundleProp{Key: prop.Key, Values: append([]string{}, prop.Values...)})
+		}
+	}
+	return merged
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedSet(set map[string]bool) []string {
+	values := make([]string, 0)
+	for value := range set {
+		values = append(values, value)
+	}
+	sort.Strings(values)
+	return values
+}
@@ -0,0 +1,80 @@
+package commands
+
+import (
+	"k8s.io/helm/pkg/chartutil"
+	"reflect"
+	"testing"
+)
+
+func TestOriginProps(t *testing.T) {
+	chrt, err := chartutil.Load("testdata/artifactory-jcr-2.2.0.tgz")
+	if err != nil {
+		t.Fatalf("Error loading test chart: %s\n", err)
+	}
+	_, artifacts, err := createFilespec(chrt, "testhelmrepo", "testdockerrepo")
+	if err != nil {
+		t.Fatalf("Error generating filespec: %s\n", err)
+	}
+	props, err := parseProps("team=platform;channel=stable,lts")
+	if err != nil {
+		t.Fatalf("Error parsing properties: %s\n", err)
+	}
+	artifacts = applyBundleProps(artifacts, bundleOptions{originProps: true, props: props})
+	expected := map[string][]releaseBundleProp{
+		"bitnami/postgresql:9.6.17-debian-10-r21": {
+			{Key: "origin.image", Values: []string{"docker.bintray.io/bitnami/postgresql:9.6.17-debian-10-r21"}},
+			{Key: "origin.registry", Values: []string{"docker.bintray.io"}},
+			{Key: "origin.chart", Values: []string{"postgresql"}},
+			{Key: "origin.parent.chart", Values: []string{"artifactory"}},
+			{Key: "origin.values.profile", Values: []string{"default"}},
+			{Key: "team", Values: []string{"platform"}},
+			{Key: "channel", Values: []string{"stable", "lts"}},
+		},
+		"alpine:3.10": {
+			{Key: "origin.image", Values: []string{"alpine:3.10"}},
+			{Key: "origin.registry", Values: []string{"docker.io"}},
+			{Key: "origin.chart", Values: []string{"artifactory"}},
+			{Key: "origin.parent.chart", Values: []string{"artifactory-jcr"}},
+			{Key: "origin.values.profile", Values: []string{"default"}},
+			{Key: "team", Values: []string{"platform"}},
+			{Key: "channel", Values: []string{"stable", "lts"}},
+		},
+		"artifactory-jcr-2.2.0.tgz": {
+			{Key: "origin.chart", Values: []string{"artifactory-jcr"}},
+			{Key: "origin.values.profile", Values: []string{"default"}},
+			{Key: "team", Values: []string{"platform"}},
+			{Key: "channel", Values: []string{"stable", "lts"}},
+		},
+	}
+	seen := map[string]bool{}
+	for _, artifact := range artifacts {
+		props, ok := expected[artifact.name]
+		if !ok {
+			continue
+		}
+		seen[artifact.name] = true
+		if !reflect.DeepEqual(artifact.addedProps, props) {
+			t.Fatalf("Properties of %s are incorrect. Expected:\n%+v\nGot:\n%+v\n", artifact.name, props, artifact.addedProps)
+		}
+	}
+	for name := range expected {
+		if !seen[name] {
+			t.Fatalf("Artifact %s is missing\n", name)
+		}
+	}
+	if _, err = parseProps("team"); err == nil {
+		t.Fatal("Expected an error parsing properties without values\n")
+	}
+}
+
+func TestMergeProps(t *testing.T) {
+	existing := []releaseBundleProp{{Key: "release", Values: []string{"1.4"}}}
+	merged := mergeProps(existing, releaseBundleProp{Key: "release", Values: []string{"1.4", "1.5"}}, releaseBundleProp{Key: "team", Values: []string{"platform"}})
+	expected := []releaseBundleProp{{Key: "release", Values: []string{"1.4", "1.5"}}, {Key: "team", Values: []string{"platform"}}}
+	if !reflect.DeepEqual(merged, expected) {
+		t.Fatalf("Merged properties are incorrect: %+v\n", merged)
+	}
+	if len(existing[0].Values) != 1 {
+		t.Fatalf("Merging properties changed the existing ones: %+v\n", existing)
+	}
+}
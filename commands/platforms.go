@@ -0,0 +1,107 @@
+package commands
+
+import (
+	"encoding/json"
+	"errors"
+	rtutils "github.com/jfrog/jfrog-cli-core/artifactory/utils"
+	"github.com/jfrog/jfrog-client-go/utils/errorutils"
+	"path"
+	"strings"
+)
+
+// dockerManifestListFile is the manifest Artifactory stores in the tag folder
+// of a multi-architecture image, next to the folders of its platform images.
+const dockerManifestListFile = "list.manifest.json"
+
+// manifestList is a Docker manifest list or an OCI image index, referencing an
+// image manifest per platform.
+type manifestList struct {
+	MediaType string `json:"mediaType"`
+	Manifests []struct {
+		Digest   string `json:"digest"`
+		Platform struct {
+			Architecture string `json:"architecture"`
+			OS           string `json:"os"`
+			Variant      string `json:"variant,omitempty"`
+		} `json:"platform"`
+	} `json:"manifests"`
+}
+
+// parsePlatforms parses a comma separated list of platforms, given as
+// os/architecture or os/architecture/variant.
+func parsePlatforms(platforms string) ([]string, error) {
+	parsed := make([]string, 0)
+	if platforms == "" {
+		return parsed, nil
+	}
+	for _, platform := range strings.Split(platforms, ",") {
+		platform = strings.TrimSpace(platform)
+		if splits := strings.Split(platform, "/"); len(splits) < 2 || len(splits) > 3 || splits[0] == "" || splits[1] == "" {
+			return nil, errorutils.CheckError(errors.New("Platform " + platform + " must be given as os/architecture or os/architecture/variant."))
+		}
+		parsed = append(parsed, platform)
+	}
+	return parsed, nil
+}
+
+// platformMatches tells whether platform is selected by filters. A filter
+// without a variant selects every variant of its architecture.
+func platformMatches(platform string, filters []string) bool {
+	if len(filters) == 0 {
+		return true
+	}
+	for _, filter := range filters {
+		if platform == filter || strings.HasPrefix(platform, filter+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// expandManifestLists adds the folders of the platform images of the
+// multi-architecture images among artifacts, whose manifest lists are found in
+// results and read with read, keeping the platforms selected by filters. It
+// returns the platforms included for each image, by artifact name.
+func expandManifestLists(artifacts []bundleArtifact, results []rtutils.SearchResult, read artifactReader, filters []string) ([]bundleArtifact, map[string][]string, error) {
+	paths := resultPaths(results)
+	platforms := map[string][]string{}
+	expanded := make([]bundleArtifact, 0)
+	for _, artifact := range artifacts {
+		if artifact.packageType == "docker" {
+			for _, p := range artifact.matches(paths) {
+				if path.Base(p) != dockerManifestListFile {
+					continue
+				}
+				content, err := readArtifact(read, p)
+				if err != nil {
+					return nil, nil, err
+				}
+				list := &manifestList{}
+				if err := json.Unmarshal(content, list); err != nil {
+					return nil, nil, errorutils.CheckError(errors.New("Failed to parse the manifest list " + p + ": " + err.Error()))
+				}
+				// The platform images are stored next to the tag folder.
+				imageFolder := path.Dir(path.Dir(p))
+				patterns := append([]string{}, artifact.patterns...)
+				included := platforms[artifact.name]
+				for _, manifest := range list.Manifests {
+					platform := manifest.Platform.OS + "/" + manifest.Platform.Architecture
+					if manifest.Platform.Variant != "" {
+						platform = platform + "/" + manifest.Platform.Variant
+					}
+					if !platformMatches(platform, filters) {
+						continue
+					}
+					patterns = append(patterns, imageFolder+"/"+strings.ReplaceAll(manifest.Digest, ":", "__")+"/")
+					if !containsString(included, platform) {
+						included = append(included, platform)
+					}
+				}
+				artifact.patterns = patterns
+				platforms[artifact.name] = included
+			}
+		}
+		expanded = append(expanded, artifact)
+	}
+	return expanded, platforms, nil
+}
@@ -0,0 +1,122 @@
+package commands
+
+import (
+	"encoding/json"
+	rtutils "github.com/jfrog/jfrog-cli-core/artifactory/utils"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// multiArchImageFiles returns the files of a multi-architecture image with a
+// platform image per config, keyed by platform, and the folders of the
+// platform images.
+func multiArchImageFiles(t *testing.T, folder string, configs map[string][]byte) (map[string][]byte, map[string]string) {
+	files, folders := map[string][]byte{}, map[string]string{}
+	list := map[string]interface{}{"schemaVersion": 2, "mediaType": "application/vnd.docker.distribution.manifest.list.v2+json"}
+	manifests := make([]map[string]interface{}, 0)
+	for _, platform := range sortedKeys(map[string]string{"linux/amd64": "", "linux/arm64/v8": "", "linux/arm/v7": ""}) {
+		config, ok := configs[platform]
+		if !ok {
+			continue
+		}
+		image := imageFiles(t, "platform", config, []byte(platform+" layer"))
+		digest := sha256Digest(image["platform/"+dockerManifestFile])
+		platformFolder := folder[:strings.LastIndex(folder, "/")] + "/" + strings.ReplaceAll(digest, ":", "__")
+		folders[platform] = platformFolder
+		for p, content := range image {
+			files[platformFolder+strings.TrimPrefix(p, "platform")] = content
+		}
+		splits := strings.Split(platform, "/")
+		spec := map[string]string{"os": splits[0], "architecture": splits[1]}
+		if len(splits) == 3 {
+			spec["variant"] = splits[2]
+		}
+		manifests = append(manifests, map[string]interface{}{"digest": digest, "platform": spec})
+	}
+	list["manifests"] = manifests
+	content, err := json.Marshal(list)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files[folder+"/"+dockerManifestListFile] = content
+	return files, folders
+}
+
+func TestExpandManifestLists(t *testing.T) {
+	files, platformFolders := multiArchImageFiles(t, "docker-local/library/busybox/1.31", map[string][]byte{
+		"linux/amd64":    []byte("amd64 config"),
+		"linux/arm64/v8": []byte("arm64 config"),
+		"linux/arm/v7":   []byte("arm config"),
+	})
+	for p, content := range imageFiles(t, "docker-local/alpine/3.10", []byte("alpine config")) {
+		files[p] = content
+	}
+	read, paths := writeArtifactoryFiles(t, files)
+	results := make([]rtutils.SearchResult, 0)
+	for _, p := range paths {
+		results = append(results, rtutils.SearchResult{Path: p})
+	}
+	artifacts := imageAndChartArtifacts(map[string]string{"busybox:1.31": "busybox:1.31", "alpine:3.10": "alpine:3.10"}, map[string]string{}, "docker-local")
+	filters, err := parsePlatforms("linux/amd64, linux/arm64")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expanded, platforms, err := expandManifestLists(artifacts, results, read, filters)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedPlatforms := map[string][]string{"busybox:1.31": {"linux/amd64", "linux/arm64/v8"}}
+	if !reflect.DeepEqual(platforms, expectedPlatforms) {
+		t.Errorf("Expected platforms %v, got %v", expectedPlatforms, platforms)
+	}
+	if !reflect.DeepEqual(expanded[0], artifacts[0]) {
+		t.Errorf("Expected alpine to be left as it is, got %+v", expanded[0])
+	}
+	folders := map[string]bool{}
+	for _, p := range expanded[1].matches(paths) {
+		folders[p[:strings.LastIndex(p, "/")]] = true
+	}
+	expectedFolders := []string{"docker-local/library/busybox/1.31", platformFolders["linux/amd64"], platformFolders["linux/arm64/v8"]}
+	if actual := sortedSet(folders); !reflect.DeepEqual(actual, sortedSet(toSet(expectedFolders))) {
+		t.Errorf("Expected the files of folders %v, got %v", expectedFolders, actual)
+	}
+
+	digests, err := resolveImageDigests(expanded, results, read)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if digests["busybox:1.31"] != sha256Digest(files["docker-local/library/busybox/1.31/"+dockerManifestListFile]) {
+		t.Errorf("Expected busybox to be pinned to its manifest list, got %s", digests["busybox:1.31"])
+	}
+
+	_, platforms, err = expandManifestLists(artifacts, results, read, []string{"linux/s390x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if included, ok := platforms["busybox:1.31"]; !ok || len(included) != 0 {
+		t.Errorf("Expected no platform to be included, got %v", platforms)
+	}
+}
+
+func TestParsePlatforms(t *testing.T) {
+	platforms, err := parsePlatforms("linux/amd64,linux/arm/v7")
+	if err != nil || !reflect.DeepEqual(platforms, []string{"linux/amd64", "linux/arm/v7"}) {
+		t.Errorf("Unexpected platforms %v, %v", platforms, err)
+	}
+	if _, err := parsePlatforms("amd64"); err == nil {
+		t.Error("Expected a platform without an os to fail")
+	}
+	if !platformMatches("linux/arm/v7", []string{"linux/arm"}) || platformMatches("linux/arm64/v8", []string{"linux/arm"}) {
+		t.Error("Expected a filter without a variant to select its variants only")
+	}
+}
+
+func toSet(values []string) map[string]bool {
+	set := map[string]bool{}
+	for _, value := range values {
+		set[value] = true
+	}
+	return set
+}
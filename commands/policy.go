@@ -0,0 +1,177 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"github.com/ghodss/yaml"
+	"github.com/jfrog/jfrog-client-go/utils/errorutils"
+	"io"
+	"io/ioutil"
+	"path"
+	"strings"
+)
+
+const (
+	deniedTagsRule          = "denied-tags"
+	allowedRegistriesRule   = "allowed-registries"
+	allowedHelmReposRule    = "allowed-helm-repos"
+	deprecatedChartsRule    = "deprecated-charts"
+	allowedLicensesRule     = "allowed-licenses"
+	requiredMaintainersRule = "required-maintainers"
+	policyError             = "error"
+	policyWarning           = "warning"
+)
+
+// bundlePolicy lists the rules the images and charts of a release bundle are
+// checked against, as read from a policy file.
+type bundlePolicy struct {
+	Rules []policyRule `json:"rules"`
+}
+
+// policyRule is a rule of a policy. Values are patterns, as in path.Match, of
+// the tags, registries or Helm repositories the rule denies or allows.
+// Violations are errors, which block the release bundle, unless the severity
+// is warning.
+type policyRule struct {
+	Rule     string   `json:"rule"`
+	Values   []string `json:"values,omitempty"`
+	Severity string   `json:"severity,omitempty"`
+}
+
+// policyViolation is an artifact breaking a rule.
+type policyViolation struct {
+	rule     string
+	severity string
+	artifact string
+	message  string
+}
+
+// loadPolicy reads the policy file at policyPath, checking its rules.
+func loadPolicy(policyPath string) (*bundlePolicy, error) {
+	content, err := ioutil.ReadFile(policyPath)
+	if err != nil {
+		return nil, errorutils.CheckError(err)
+	}
+	policy := &bundlePolicy{}
+	if err := yaml.Unmarshal(content, policy); err != nil {
+		return nil, errorutils.CheckError(errors.New("Failed to parse the policy " + policyPath + ": " + err.Error()))
+	}
+	for i, rule := range policy.Rules {
+		switch rule.Rule {
+		case deniedTagsRule, allowedRegistriesRule, allowedHelmReposRule, allowedLicensesRule:
+			if len(rule.Values) == 0 {
+				return nil, errorutils.CheckError(errors.New("Policy rule " + rule.Rule + " has no values."))
+			}
+		case deprecatedChartsRule, requiredMaintainersRule:
+		default:
+			return nil, errorutils.CheckError(errors.New("Unknown policy rule: " + rule.Rule))
+		}
+		for _, value := range rule.Values {
+			if _, err := path.Match(value, ""); err != nil {
+				return nil, errorutils.CheckError(errors.New("Policy rule " + rule.Rule + " has an invalid pattern: " + value))
+			}
+		}
+		switch rule.Severity {
+		case "":
+			policy.Rules[i].Severity = policyError
+		case policyError, policyWarning:
+		default:
+			return nil, errorutils.CheckError(errors.New("Policy rule " + rule.Rule + " must have a severity of error or warning."))
+		}
+	}
+	return policy, nil
+}
+
+// evaluate checks the images and chart archives among artifacts, created from
+// an image reference or a chart archive key, against the rules of the policy.
+func (bp *bundlePolicy) evaluate(artifacts []bundleArtifact) []policyViolation {
+	violations := make([]policyViolation, 0)
+	for _, artifact := range artifacts {
+		if artifact.source == "" {
+			continue
+		}
+		for _, rule := range bp.Rules {
+			message := ""
+			switch {
+			case artifact.packageType == "docker" && rule.Rule == deniedTagsRule:
+				if tag := parseImageReference(artifact.source).tag; tag != "" && matchesAny(rule.Values, tag) {
+					message = "the tag " + tag + " is denied"
+				}
+			case artifact.packageType == "docker" && rule.Rule == allowedRegistriesRule:
+				registry := parseImageReference(artifact.source).registry
+				if registry == "" {
+					registry = defaultRegistry
+				}
+				if !matchesAny(rule.Values, registry) {
+					message = "the registry " + registry + " isn't allowed"
+				}
+			case artifact.packageType == "helm" && rule.Rule == allowedHelmReposRule:
+				if repo := strings.SplitN(artifact.source, "/", 2)[0]; !matchesAny(rule.Values, repo) {
+					message = "the Helm repository " + repo + " isn't allowed"
+				}
+			case artifact.packageType == "helm" && rule.Rule == deprecatedChartsRule:
+				for _, origin := range artifact.origins {
+					if origin.deprecated {
+						message = "the chart is deprecated"
+					}
+				}
+			case rule.Rule == allowedLicensesRule:
+				if origin, ok := chartArchiveOrigin(artifact); ok {
+					message = checkLicense(origin.license, rule.Values)
+				}
+			case rule.Rule == requiredMaintainersRule:
+				if origin, ok := chartArchiveOrigin(artifact); ok && !origin.maintained {
+					message = "the chart has no maintainers"
+				}
+			}
+			if message != "" {
+				violations = append(violations, policyViolation{rule: rule.Rule, severity: rule.Severity, artifact: artifact.name, message: message})
+			}
+		}
+	}
+	return violations
+}
+
+// checkLicense describes why license isn't allowed, if it isn't: every license
+// it names must be among allowed.
+func checkLicense(license string, allowed []string) string {
+	ids := licenseIds(license)
+	if len(ids) == 0 {
+		return "the chart declares no license"
+	}
+	for _, id := range ids {
+		if !matchesAny(allowed, id) {
+			return "the license " + license + " isn't allowed"
+		}
+	}
+	return ""
+}
+
+func matchesAny(patterns []string, value string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, value); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// reportPolicyViolations prints violations, and fails if any of them is an
+// error.
+func reportPolicyViolations(out io.Writer, violations []policyViolation) error {
+	if len(violations) == 0 {
+		return nil
+	}
+	fmt.Fprintln(out, "Policy violations:")
+	errorCount := 0
+	for _, violation := range violations {
+		fmt.Fprintln(out, "- "+violation.severity+": "+violation.artifact+": "+violation.message+" ("+violation.rule+")")
+		if violation.severity == policyError {
+			errorCount++
+		}
+	}
+	if errorCount > 0 {
+		return errorutils.CheckError(fmt.Errorf("Found %d policy violations with a severity of error.", errorCount))
+	}
+	return nil
+}
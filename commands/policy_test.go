@@ -0,0 +1,74 @@
+package commands
+
+import (
+	"io/ioutil"
+	"k8s.io/helm/pkg/chartutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEvaluatePolicy(t *testing.T) {
+	policy, err := loadPolicy("testdata/policy.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	chrt, err := chartutil.Load("testdata/artifactory-jcr-2.2.0.tgz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	chrt.Dependencies[0].Metadata.Deprecated = true
+	_, artifacts, err := createFilespec(chrt, "helm-remote", "docker-local")
+	if err != nil {
+		t.Fatal(err)
+	}
+	artifacts = append(artifacts, imageAndChartArtifacts(map[string]string{"registry.example.com/app": "registry.example.com/app"},
+		map[string]string{"helm-local/app-1.0.0.tgz": "app-1.0.0.tgz"}, "docker-local")...)
+
+	violations := policy.evaluate(artifacts)
+	actual := make([]string, 0)
+	for _, violation := range violations {
+		actual = append(actual, violation.severity+" "+violation.rule+" "+violation.artifact+": "+violation.message)
+	}
+	expected := []string{
+		"error allowed-registries alpine:3.10: the registry docker.io isn't allowed",
+		"error allowed-helm-repos artifactory-9.4.0.tgz: the Helm repository helm-remote isn't allowed",
+		"warning deprecated-charts artifactory-9.4.0.tgz: the chart is deprecated",
+		"error allowed-helm-repos artifactory-jcr-2.2.0.tgz: the Helm repository helm-remote isn't allowed",
+		"error allowed-helm-repos postgresql-8.7.3.tgz: the Helm repository helm-remote isn't allowed",
+		"error denied-tags app:latest: the tag latest is denied",
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected violations:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(actual, "\n"))
+	}
+	if err := reportPolicyViolations(ioutil.Discard, violations); err == nil || !strings.Contains(err.Error(), "Found 5 policy violations") {
+		t.Errorf("Expected the errors to block the release bundle, got %v", err)
+	}
+	if err := reportPolicyViolations(ioutil.Discard, violations[2:3]); err != nil {
+		t.Errorf("Expected warnings not to block the release bundle, got %v", err)
+	}
+}
+
+func TestLoadPolicyInvalidRule(t *testing.T) {
+	dir, err := ioutil.TempDir("", "policy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for content, message := range map[string]string{
+		"rules:\n- rule: allowed-base-images\n":                            "Unknown policy rule: allowed-base-images",
+		"rules:\n- rule: denied-tags\n":                                    "Policy rule denied-tags has no values.",
+		"rules:\n- rule: deprecated-charts\n  severity: fatal\n":           "must have a severity of error or warning",
+		"rules:\n- rule: allowed-registries\n  values: [\"[docker.io\"]\n": "invalid pattern",
+	} {
+		policyPath := filepath.Join(dir, "policy.yaml")
+		if err := ioutil.WriteFile(policyPath, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := loadPolicy(policyPath); err == nil || !strings.Contains(err.Error(), message) {
+			t.Errorf("Expected %q loading:\n%s\ngot %v", message, content, err)
+		}
+	}
+}
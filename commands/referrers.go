@@ -0,0 +1,108 @@
+package commands
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/jfrog/jfrog-cli-core/artifactory/spec"
+	rtutils "github.com/jfrog/jfrog-cli-core/artifactory/utils"
+	"github.com/jfrog/jfrog-client-go/utils/errorutils"
+	"path"
+	"strings"
+)
+
+// artifactSearcher searches Artifactory for the entries of a file spec.
+type artifactSearcher func(specfiles *spec.SpecFiles) ([]rtutils.SearchResult, error)
+
+// referrerTag is a tag cosign, or a registry without the OCI referrers API,
+// stores artifacts referring to an image under: sha256-<hex><suffix>.
+type referrerTag struct {
+	suffix string
+	kind   string
+}
+
+var referrerTags = []referrerTag{
+	{suffix: ".sig", kind: "signature"},
+	{suffix: ".att", kind: "attestation"},
+	{suffix: ".sbom", kind: "SBOM"},
+	// The OCI referrers tag schema lists the referrers in an image index.
+	{suffix: "", kind: "referrers"},
+}
+
+// findReferrers finds the signatures, attestations, SBOMs and OCI referrers of
+// the images among artifacts which have a digest, as tags next to the tag
+// folder of the image, whose manifest is among paths. Each one found is
+// returned as an artifact named after its image, with the properties and
+// repository mappings of the image, along with the search results of their
+// tags. The image manifests an OCI referrers index lists, stored in folders
+// named after their digest, are included too.
+func findReferrers(artifacts []bundleArtifact, paths []string, digests map[string]string, search artifactSearcher, read artifactReader) ([]bundleArtifact, []rtutils.SearchResult, error) {
+	candidates := make([]bundleArtifact, 0)
+	for _, artifact := range artifacts {
+		digest, ok := digests[artifact.name]
+		if !ok || artifact.packageType != "docker" {
+			continue
+		}
+		manifests := tagManifests(artifact.matches(paths))
+		if len(manifests) == 0 {
+			continue
+		}
+		imageFolder := path.Dir(path.Dir(manifests[0]))
+		tag := strings.Replace(digest, ":", "-", 1)
+		for _, referrer := range referrerTags {
+			candidates = append(candidates, bundleArtifact{
+				name:        artifact.name + " " + referrer.kind,
+				packageType: "docker",
+				source:      artifact.source,
+				origins:     artifact.origins,
+				patterns:    []string{imageFolder + "/" + tag + referrer.suffix + "/"},
+				addedProps:  artifact.addedProps,
+				targetRepos: artifact.targetRepos,
+			})
+		}
+	}
+	referrers := make([]bundleArtifact, 0)
+	if len(candidates) == 0 {
+		return referrers, make([]rtutils.SearchResult, 0), nil
+	}
+	results, err := search(createSpecFiles(candidates))
+	if err != nil {
+		return nil, nil, err
+	}
+	found := resultPaths(results)
+	for _, candidate := range candidates {
+		matched := candidate.matches(found)
+		if len(matched) == 0 {
+			continue
+		}
+		for _, p := range matched {
+			if path.Base(p) != dockerManifestListFile {
+				continue
+			}
+			folders, err := referencedManifestFolders(p, read)
+			if err != nil {
+				return nil, nil, err
+			}
+			candidate.patterns = append(candidate.patterns, folders...)
+		}
+		referrers = append(referrers, candidate)
+	}
+	return referrers, results, nil
+}
+
+// referencedManifestFolders returns the folders of the manifests an image
+// index lists, stored next to the tag folder of the index.
+func referencedManifestFolders(indexPath string, read artifactReader) ([]string, error) {
+	content, err := readArtifact(read, indexPath)
+	if err != nil {
+		return nil, err
+	}
+	list := &manifestList{}
+	if err := json.Unmarshal(content, list); err != nil {
+		return nil, errorutils.CheckError(errors.New("Failed to parse the image index " + indexPath + ": " + err.Error()))
+	}
+	folders := make([]string, 0)
+	for _, manifest := range list.Manifests {
+		folders = append(folders, path.Dir(path.Dir(indexPath))+"/"+strings.ReplaceAll(manifest.Digest, ":", "__")+"/")
+	}
+	return folders, nil
+}
@@ -0,0 +1,70 @@
+package commands
+
+import (
+	"encoding/json"
+	"github.com/jfrog/jfrog-cli-core/artifactory/spec"
+	rtutils "github.com/jfrog/jfrog-cli-core/artifactory/utils"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFindReferrers(t *testing.T) {
+	files := imageFiles(t, "docker-local/alpine/3.10", []byte("alpine config"), []byte("alpine layer"))
+	digest := sha256Digest(files["docker-local/alpine/3.10/"+dockerManifestFile])
+	tag := "docker-local/alpine/" + strings.Replace(digest, ":", "-", 1)
+	for p, content := range imageFiles(t, tag+".sig", []byte("signature config"), []byte("signature")) {
+		files[p] = content
+	}
+	sbom := imageFiles(t, "sbom", []byte("sbom config"), []byte("sbom"))
+	sbomDigest := sha256Digest(sbom["sbom/"+dockerManifestFile])
+	sbomFolder := "docker-local/alpine/" + strings.Replace(sbomDigest, ":", "__", 1)
+	for p, content := range sbom {
+		files[sbomFolder+strings.TrimPrefix(p, "sbom")] = content
+	}
+	index, err := json.Marshal(map[string]interface{}{"schemaVersion": 2, "manifests": []map[string]string{{"digest": sbomDigest}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	files[tag+"/"+dockerManifestListFile] = index
+	read, paths := writeArtifactoryFiles(t, files)
+
+	searched := make([]string, 0)
+	search := func(specfiles *spec.SpecFiles) ([]rtutils.SearchResult, error) {
+		results := make([]rtutils.SearchResult, 0)
+		for _, file := range specfiles.Files {
+			searched = append(searched, file.Pattern)
+			for _, p := range paths {
+				if patternToRegexp(file.Pattern).MatchString(p) {
+					results = append(results, rtutils.SearchResult{Path: p})
+				}
+			}
+		}
+		return results, nil
+	}
+	artifacts := imageAndChartArtifacts(map[string]string{"alpine:3.10": "alpine:3.10", "busybox:1.31": "busybox:1.31"}, map[string]string{}, "docker-local")
+	artifacts = pinImageDigests(artifacts, map[string]string{"alpine:3.10": digest})
+
+	referrers, results, err := findReferrers(artifacts, paths, map[string]string{"alpine:3.10": digest}, search, read)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(searched) != 4 {
+		t.Errorf("Expected the tags of alpine only to be searched, got %v", searched)
+	}
+	if len(results) != 4 {
+		t.Errorf("Expected the files of the signature and the index, got %v", results)
+	}
+	if len(referrers) != 2 || referrers[0].name != "alpine:3.10 signature" || referrers[1].name != "alpine:3.10 referrers" {
+		t.Fatalf("Unexpected referrers %+v", referrers)
+	}
+	if !reflect.DeepEqual(referrers[0].patterns, []string{tag + ".sig/"}) {
+		t.Errorf("Unexpected signature patterns %v", referrers[0].patterns)
+	}
+	if !reflect.DeepEqual(referrers[1].patterns, []string{tag + "/", sbomFolder + "/"}) {
+		t.Errorf("Unexpected referrers patterns %v", referrers[1].patterns)
+	}
+	if !reflect.DeepEqual(referrers[0].addedProps, []releaseBundleProp{{Key: imageDigestProp, Values: []string{digest}}}) {
+		t.Errorf("Expected the signature to keep the properties of its image, got %v", referrers[0].addedProps)
+	}
+}
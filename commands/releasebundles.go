@@ -0,0 +1,252 @@
+package commands
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/jfrog/jfrog-cli-core/artifactory/spec"
+	rtutils "github.com/jfrog/jfrog-cli-core/artifactory/utils"
+	"github.com/jfrog/jfrog-cli-core/utils/config"
+	artifactoryUtils "github.com/jfrog/jfrog-client-go/artifactory/services/utils"
+	distributionServices "github.com/jfrog/jfrog-client-go/distribution/services"
+	distributionServicesUtils "github.com/jfrog/jfrog-client-go/distribution/services/utils"
+	clientutils "github.com/jfrog/jfrog-client-go/utils"
+	"github.com/jfrog/jfrog-client-go/utils/errorutils"
+	"github.com/jfrog/jfrog-client-go/utils/log"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// releaseBundleVersion is a release bundle version, as returned by Distribution.
+type releaseBundleVersion struct {
+	Name              string                                  `json:"name"`
+	Version           string                                  `json:"version"`
+	StoringRepository string                                  `json:"storing_repository"`
+	Description       string                                  `json:"description"`
+	ReleaseNotes      *distributionServicesUtils.ReleaseNotes `json:"release_notes"`
+	Spec              releaseBundleSpec                       `json:"spec"`
+	Artifacts         []releaseBundleArtifact                 `json:"artifacts"`
+}
+
+type releaseBundleSpec struct {
+	Queries []releaseBundleQuery `json:"queries"`
+}
+
+// releaseBundleQuery is a release bundle query, with the properties added to
+// the artifacts it finds and the paths they're stored at on the edge nodes.
+type releaseBundleQuery struct {
+	QueryName  string                 `json:"query_name,omitempty"`
+	Aql        string                 `json:"aql"`
+	AddedProps []releaseBundleProp    `json:"added_props,omitempty"`
+	Mappings   []releaseBundleMapping `json:"mappings,omitempty"`
+}
+
+type releaseBundleArtifact struct {
+	// Distribution versions differ in the case they use for the source path.
+	SourceRepoPath      string              `json:"source_repo_path"`
+	SourceRepoPathCamel string              `json:"sourceRepoPath"`
+	Checksum            string              `json:"checksum"`
+	Props               []releaseBundleProp `json:"props"`
+}
+
+type releaseBundleProp struct {
+	Key    string   `json:"key"`
+	Values []string `json:"values"`
+}
+
+// releaseBundleCreateBody is the body of a release bundle creation request.
+// Unlike distributionServicesUtils.ReleaseBundleBody, its queries can add
+// properties.
+type releaseBundleCreateBody struct {
+	Name              string                                  `json:"name"`
+	Version           string                                  `json:"version"`
+	DryRun            bool                                    `json:"dry_run"`
+	SignImmediately   bool                                    `json:"sign_immediately,omitempty"`
+	StoringRepository string                                  `json:"storing_repository,omitempty"`
+	Description       string                                  `json:"description,omitempty"`
+	ReleaseNotes      *distributionServicesUtils.ReleaseNotes `json:"release_notes,omitempty"`
+	Spec              releaseBundleSpec                       `json:"spec"`
+}
+
+func (rba releaseBundleArtifact) path() string {
+	if rba.SourceRepoPath != "" {
+		return rba.SourceRepoPath
+	}
+	return rba.SourceRepoPathCamel
+}
+
+// parseBundleNameAndVersion splits a release bundle given as name/version.
+func parseBundleNameAndVersion(bundle string) (string, string, error) {
+	i := strings.LastIndex(bundle, "/")
+	if i <= 0 || i == len(bundle)-1 {
+		return "", "", errorutils.CheckError(errors.New("Release bundle " + bundle + " must be given as name/version."))
+	}
+	return bundle[:i], bundle[i+1:], nil
+}
+
+func getReleaseBundleVersion(rtDetails *config.ArtifactoryDetails, name, version string) (*releaseBundleVersion, error) {
+	manager, err := rtutils.CreateDistributionServiceManager(rtDetails, false)
+	if err != nil {
+		return nil, err
+	}
+	distDetails, err := rtDetails.CreateDistAuthConfig()
+	if err != nil {
+		return nil, err
+	}
+	httpClientDetails := distDetails.CreateHttpClientDetails()
+	bundleUrl := distDetails.GetUrl() + "api/v1/release_bundle/" + url.PathEscape(name) + "/" + url.PathEscape(version) + "?format=json"
+	resp, body, _, err := manager.Client().SendGet(bundleUrl, true, &httpClientDetails)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, errorutils.CheckError(errors.New("Distribution response: " + resp.Status + "\n" + clientutils.IndentJson(body)))
+	}
+	bundle := &releaseBundleVersion{}
+	err = json.Unmarshal(body, bundle)
+	return bundle, errorutils.CheckError(err)
+}
+
+// extendReleaseBundle adds the artifacts of an existing release bundle version
+// to artifacts, keeping their properties, and uses its description, release
+// notes and storing repository where params doesn't set them. Artifacts which
+// are already in the bundle are dropped. The original artifacts keep the paths
+// the path mappings of its queries store them at on the edge nodes.
+func extendReleaseBundle(params distributionServicesUtils.ReleaseBundleParams, artifacts []bundleArtifact, original *releaseBundleVersion) (distributionServicesUtils.ReleaseBundleParams, []bundleArtifact, error) {
+	if params.Description == "" {
+		params.Description = original.Description
+	}
+	if params.ReleaseNotes == "" && original.ReleaseNotes != nil {
+		params.ReleaseNotes = original.ReleaseNotes.Content
+		params.ReleaseNotesSyntax = original.ReleaseNotes.Syntax
+	}
+	if params.StoringRepository == "" {
+		params.StoringRepository = original.StoringRepository
+	}
+	// The artifacts don't tell which query found them, so the mappings of all
+	// the queries are tried in turn.
+	mappings := make([]releaseBundleMapping, 0)
+	for _, query := range original.Spec.Queries {
+		mappings = append(mappings, query.Mappings...)
+	}
+	extended := make([]bundleArtifact, 0)
+	paths := make([]string, 0)
+	for _, artifact := range original.Artifacts {
+		path := artifact.path()
+		target, err := mapPath(path, mappings)
+		if err != nil {
+			return params, nil, err
+		}
+		originalArtifact := bundleArtifact{name: path, addedProps: artifact.Props}
+		if target != "" {
+			originalArtifact.files = []spec.File{{Pattern: path, Target: target}}
+		} else {
+			originalArtifact.patterns = []string{path}
+		}
+		extended = append(extended, originalArtifact)
+		paths = append(paths, path)
+	}
+	for _, artifact := range artifacts {
+		if !artifact.foundIn(paths) {
+			extended = append(extended, artifact)
+		}
+	}
+	return params, extended, nil
+}
+
+// createReleaseBundleBody creates the body of a request creating a release
+// bundle with artifacts, with one query per file spec entry.
+func createReleaseBundleBody(params distributionServicesUtils.ReleaseBundleParams, artifacts []bundleArtifact, dryRun bool) (*releaseBundleCreateBody, error) {
+	params.SpecFiles = make([]*artifactoryUtils.ArtifactoryCommonParams, 0)
+	for _, file := range createSpecFiles(artifacts).Files {
+		commonParams := file.ToArtifactoryCommonParams()
+		// ToArtifactoryCommonParams leaves the entry non-recursive.
+		if file.Recursive != "" {
+			recursive, err := file.IsRecursive(true)
+			if err != nil {
+				return nil, errorutils.CheckError(err)
+			}
+			commonParams.Recursive = recursive
+		}
+		params.SpecFiles = append(params.SpecFiles, commonParams)
+	}
+	body, err := distributionServicesUtils.CreateBundleBody(params, dryRun)
+	if err != nil {
+		return nil, err
+	}
+	queries := make([]releaseBundleQuery, 0)
+	for _, artifact := range artifacts {
+		for _, file := range artifact.specFiles() {
+			query := body.BundleSpec.Queries[len(queries)]
+			bundleQuery := releaseBundleQuery{QueryName: query.QueryName, Aql: query.Aql, AddedProps: artifact.addedProps}
+			if mapping := pathMappingFromSpecFile(file); mapping != nil {
+				bundleQuery.Mappings = []releaseBundleMapping{*mapping}
+			}
+			queries = append(queries, bundleQuery)
+		}
+	}
+	return &releaseBundleCreateBody{
+		Name:              params.Name,
+		Version:           params.Version,
+		DryRun:            body.DryRun,
+		SignImmediately:   body.SignImmediately,
+		StoringRepository: body.StoringRepository,
+		Description:       body.Description,
+		ReleaseNotes:      body.ReleaseNotes,
+		Spec:              releaseBundleSpec{Queries: queries},
+	}, nil
+}
+
+func createReleaseBundle(rtDetails *config.ArtifactoryDetails, params distributionServicesUtils.ReleaseBundleParams, artifacts []bundleArtifact, dryRun bool) error {
+	body, err := createReleaseBundleBody(params, artifacts, dryRun)
+	if err != nil {
+		return err
+	}
+	manager, err := rtutils.CreateDistributionServiceManager(rtDetails, dryRun)
+	if err != nil {
+		return err
+	}
+	distDetails, err := rtDetails.CreateDistAuthConfig()
+	if err != nil {
+		return err
+	}
+	content, err := json.Marshal(body)
+	if err != nil {
+		return errorutils.CheckError(err)
+	}
+	httpClientDetails := distDetails.CreateHttpClientDetails()
+	distributionServicesUtils.AddGpgPassphraseHeader(params.GpgPassphrase, &httpClientDetails.Headers)
+	artifactoryUtils.SetContentType("application/json", &httpClientDetails.Headers)
+	resp, respBody, err := manager.Client().SendPost(distDetails.GetUrl()+"api/v1/release_bundle", content, &httpClientDetails)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		return errorutils.CheckError(errors.New("Distribution response: " + resp.Status + "\n" + clientutils.IndentJson(respBody)))
+	}
+	log.Debug("Distribution response: ", resp.Status)
+	log.Debug(clientutils.IndentJson(respBody))
+	return nil
+}
+
+// signReleaseBundle signs the release bundle version of params, storing it in
+// the storing repository of params.
+func signReleaseBundle(rtDetails *config.ArtifactoryDetails, params distributionServicesUtils.ReleaseBundleParams) error {
+	manager, err := rtutils.CreateDistributionServiceManager(rtDetails, false)
+	if err != nil {
+		return err
+	}
+	signParams := distributionServices.NewSignBundleParams(params.Name, params.Version)
+	signParams.StoringRepository = params.StoringRepository
+	signParams.GpgPassphrase = params.GpgPassphrase
+	return manager.SignReleaseBundle(signParams)
+}
+
+// deleteReleaseBundle deletes a release bundle version from Distribution.
+func deleteReleaseBundle(rtDetails *config.ArtifactoryDetails, name, version string) error {
+	manager, err := rtutils.CreateDistributionServiceManager(rtDetails, false)
+	if err != nil {
+		return err
+	}
+	return manager.DeleteLocalReleaseBundle(distributionServices.NewDeleteReleaseBundleParams(name, version))
+}
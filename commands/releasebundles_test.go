@@ -0,0 +1,138 @@
+package commands
+
+import (
+	"encoding/json"
+	"github.com/jfrog/jfrog-cli-core/utils/config"
+	"github.com/jfrog/jfrog-cli-core/utils/coreutils"
+	distributionServicesUtils "github.com/jfrog/jfrog-client-go/distribution/services/utils"
+	"io/ioutil"
+	"k8s.io/helm/pkg/chartutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func readTestReleaseBundle(t *testing.T) *releaseBundleVersion {
+	content, err := ioutil.ReadFile("testdata/releasebundles/platform-1.4.json")
+	if err != nil {
+		t.Fatalf("Error reading test release bundle: %s\n", err)
+	}
+	bundle := &releaseBundleVersion{}
+	if err = json.Unmarshal(content, bundle); err != nil {
+		t.Fatalf("Error parsing test release bundle: %s\n", err)
+	}
+	return bundle
+}
+
+func TestGetReleaseBundleVersion(t *testing.T) {
+	home, err := ioutil.TempDir("", "jfrog-home")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(home)
+	defer os.Setenv(coreutils.HomeDir, os.Getenv(coreutils.HomeDir))
+	os.Setenv(coreutils.HomeDir, home)
+	content, err := ioutil.ReadFile("testdata/releasebundles/platform-1.4.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/release_bundle/platform/1.4" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write(content)
+	}))
+	defer server.Close()
+	rtDetails := &config.ArtifactoryDetails{Url: server.URL + "/artifactory/", DistributionUrl: server.URL + "/", User: "admin", Password: "password"}
+	bundle, err := getReleaseBundleVersion(rtDetails, "platform", "1.4")
+	if err != nil {
+		t.Fatalf("Error getting release bundle: %s\n", err)
+	}
+	if !reflect.DeepEqual(bundle, readTestReleaseBundle(t)) {
+		t.Fatalf("Release bundle is incorrect: %+v\n", bundle)
+	}
+	if _, err = getReleaseBundleVersion(rtDetails, "platform", "1.5"); err == nil {
+		t.Fatal("Expected an error getting a missing release bundle\n")
+	}
+}
+
+func TestExtendReleaseBundle(t *testing.T) {
+	chrt, err := chartutil.Load("testdata/acs-engine-autoscaler-2.2.2.tgz")
+	if err != nil {
+		t.Fatalf("Error loading test chart: %s\n", err)
+	}
+	_, artifacts, err := createFilespec(chrt, "testhelmrepo", "testdockerrepo")
+	if err != nil {
+		t.Fatalf("Error creating spec: %s\n", err)
+	}
+	artifacts = append(artifacts, newBundleArtifact("artifactory-jcr-2.2.0.tgz", "testhelmrepo", "artifactory-jcr-2.2.0.tgz"))
+	params := distributionServicesUtils.NewReleaseBundleParams("platform", "1.5")
+	params.Description = "Platform with autoscaling"
+	params, extended, err := extendReleaseBundle(params, artifacts, readTestReleaseBundle(t))
+	if err != nil {
+		t.Fatalf("Error extending release bundle: %s\n", err)
+	}
+	if params.Description != "Platform with autoscaling" || params.ReleaseNotes != "# Platform 1.4" || params.ReleaseNotesSyntax != distributionServicesUtils.Markdown || params.StoringRepository != "release-bundles" {
+		t.Fatalf("Release bundle parameters are incorrect: %+v\n", params)
+	}
+	names := make([]string, 0)
+	for _, artifact := range extended {
+		names = append(names, artifact.name)
+	}
+	expectedNames := []string{"testhelmrepo/artifactory-jcr-2.2.0.tgz", "generic-local/installers/platform-1.4.zip", "acs-engine-autoscaler-2.2.2.tgz"}
+	if !reflect.DeepEqual(names, expectedNames) {
+		t.Fatalf("Extended artifacts are incorrect. Expected:\n%v\nGot:\n%v\n", expectedNames, names)
+	}
+
+	body, err := createReleaseBundleBody(params, extended, true)
+	if err != nil {
+		t.Fatalf("Error creating release bundle body: %s\n", err)
+	}
+	if !body.DryRun || body.Name != "platform" || body.Version != "1.5" || body.ReleaseNotes.Content != "# Platform 1.4" {
+		t.Fatalf("Release bundle body is incorrect: %+v\n", body)
+	}
+	queries := body.Spec.Queries
+	if len(queries) != 4 {
+		t.Fatalf("Expected 4 queries, got %d\n", len(queries))
+	}
+	approved := releaseBundleProp{Key: "approved", Values: []string{"qa", "security"}}
+	if len(queries[0].AddedProps) != 2 || !reflect.DeepEqual(queries[0].AddedProps[1], approved) {
+		t.Fatalf("Properties of the first query are incorrect: %+v\n", queries[0].AddedProps)
+	}
+	if len(queries[1].AddedProps) != 1 || queries[2].AddedProps != nil || queries[3].AddedProps != nil {
+		t.Fatalf("Properties of the queries are incorrect: %+v\n", queries)
+	}
+}
+
+func TestExtendReleaseBundleMappings(t *testing.T) {
+	original := readTestReleaseBundle(t)
+	original.Spec.Queries[0].Mappings = []releaseBundleMapping{{Input: "^testhelmrepo/(.*)$", Output: "edge-helm/$1"}}
+	params := distributionServicesUtils.NewReleaseBundleParams("platform", "1.5")
+	params, extended, err := extendReleaseBundle(params, nil, original)
+	if err != nil {
+		t.Fatalf("Error extending release bundle: %s\n", err)
+	}
+	body, err := createReleaseBundleBody(params, extended, true)
+	if err != nil {
+		t.Fatalf("Error creating release bundle body: %s\n", err)
+	}
+	queries := body.Spec.Queries
+	if len(queries) != 2 {
+		t.Fatalf("Expected 2 queries, got %d\n", len(queries))
+	}
+	expected := []releaseBundleMapping{{Input: `^testhelmrepo/artifactory-jcr-2\.2\.0\.tgz$`, Output: "edge-helm/artifactory-jcr-2.2.0.tgz"}}
+	if !reflect.DeepEqual(queries[0].Mappings, expected) {
+		t.Fatalf("Mappings of the mapped artifact are incorrect. Expected:\n%+v\nGot:\n%+v\n", expected, queries[0].Mappings)
+	}
+	if queries[1].Mappings != nil {
+		t.Fatalf("Unmapped artifact has mappings: %+v\n", queries[1].Mappings)
+	}
+
+	original.Spec.Queries[0].Mappings = []releaseBundleMapping{{Input: "testhelmrepo/(", Output: "edge-helm/$1"}}
+	if _, _, err = extendReleaseBundle(params, nil, original); err == nil {
+		t.Fatal("Expected an error for an invalid path mapping\n")
+	}
+}
@@ -0,0 +1,241 @@
+package commands
+
+import (
+	"github.com/ghodss/yaml"
+	"github.com/jfrog/jfrog-client-go/utils/errorutils"
+	"k8s.io/helm/pkg/chartutil"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+	"k8s.io/helm/pkg/renderutil"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const relocatedVersionSuffix = "-relocated"
+
+// imageRelocation moves the images a chart uses under prefix, a registry
+// optionally followed by a repository path.
+type imageRelocation struct {
+	prefix string
+	// targets maps the images found in the chart to their relocated reference.
+	targets map[string]string
+	// names maps the names of those images, without registry, to their
+	// relocated reference, and repositories lists their repositories.
+	names        map[string]string
+	repositories map[string]bool
+}
+
+func newImageRelocation(images map[string]string, prefix string) *imageRelocation {
+	r := &imageRelocation{prefix: strings.TrimSuffix(prefix, "/"), targets: map[string]string{}, names: map[string]string{}, repositories: map[string]bool{}}
+	for ref := range images {
+		if r.relocated(ref) {
+			continue
+		}
+		image := parseImageReference(ref)
+		target := r.prefix + "/" + image.repository
+		if image.digest != "" {
+			target = target + "@" + image.digest
+		} else {
+			target = target + ":" + image.tag
+		}
+		r.targets[ref] = target
+		r.names[image.name()] = target
+		r.repositories[image.repository] = true
+	}
+	return r
+}
+
+func (r *imageRelocation) relocated(ref string) bool {
+	return strings.HasPrefix(ref, r.prefix+"/")
+}
+
+// relocateChart rewrites chrt so that the images it uses are pulled from under
+// prefix, and sets its version. Images are relocated in the values of chrt and
+// of its dependencies, and in their templates for those which values don't
+// set. It returns the images which couldn't be relocated. chrt must not have
+// been rendered, and is only rendered through copies, so that it can be saved.
+func relocateChart(chrt *chart.Chart, prefix, version string) ([]string, error) {
+	images, err := renderImages(chrt)
+	if err != nil {
+		return nil, err
+	}
+	r := newImageRelocation(images, prefix)
+	if err := r.relocateValues(chrt); err != nil {
+		return nil, err
+	}
+	remaining, err := r.unrelocatedImages(chrt)
+	if err != nil {
+		return nil, err
+	}
+	if len(remaining) > 0 {
+		r.relocateTemplates(chrt, remaining)
+		remaining, err = r.unrelocatedImages(chrt)
+		if err != nil {
+			return nil, err
+		}
+	}
+	chrt.Metadata.Version = version
+	return remaining, nil
+}
+
+func renderImages(chrt *chart.Chart) (map[string]string, error) {
+	return renderImagesWithValues(chrt, "{}")
+}
+
+func renderImagesWithValues(chrt *chart.Chart, raw string) (map[string]string, error) {
+	files, err := renderutil.Render(copyChart(chrt), &chart.Config{Raw: raw}, renderutil.Options{})
+	if err != nil {
+		return nil, errorutils.CheckError(err)
+	}
+	return extractImages(files), nil
+}
+
+// copyChart copies chrt deeply enough to render the copy. Rendering removes
+// the disabled dependencies of a chart and replaces its values with their
+// merge with those of its dependencies, so a chart which has been rendered
+// isn't the one of its archive anymore.
+func copyChart(chrt *chart.Chart) *chart.Chart {
+	copied := &chart.Chart{Files: chrt.Files}
+	if chrt.Metadata != nil {
+		metadata := *chrt.Metadata
+		copied.Metadata = &metadata
+	}
+	if chrt.Values != nil {
+		copied.Values = &chart.Config{Raw: chrt.Values.Raw, Values: chrt.Values.Values}
+	}
+	for _, template := range chrt.Templates {
+		copied.Templates = append(copied.Templates, &chart.Template{Name: template.Name, Data: append([]byte(nil), template.Data...)})
+	}
+	for _, dep := range chrt.Dependencies {
+		copied.Dependencies = append(copied.Dependencies, copyChart(dep))
+	}
+	return copied
+}
+
+func (r *imageRelocation) unrelocatedImages(chrt *chart.Chart) ([]string, error) {
+	images, err := renderImages(chrt)
+	if err != nil {
+		return nil, err
+	}
+	remaining := make([]string, 0)
+	for ref := range images {
+		if !r.relocated(ref) {
+			remaining = append(remaining, ref)
+		}
+	}
+	sort.Strings(remaining)
+	return remaining, nil
+}
+
+// relocateValues rewrites the values of chrt and of its dependencies which
+// set images. Values files without images are left as they are.
+func (r *imageRelocation) relocateValues(chrt *chart.Chart) error {
+	if chrt.Values != nil && chrt.Values.Raw != "" {
+		values, err := chartutil.ReadValues([]byte(chrt.Values.Raw))
+		if err != nil {
+			return errorutils.CheckError(err)
+		}
+		relocated, changed := r.relocateValue(map[string]interface{}(values))
+		if changed {
+			raw, err := yaml.Marshal(relocated)
+			if err != nil {
+				return errorutils.CheckError(err)
+			}
+			chrt.Values.Raw = string(raw)
+		}
+	}
+	for _, dep := range chrt.Dependencies {
+		if err := r.relocateValues(dep); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// relocateValue relocates an image given as a string, as in image: repo:tag,
+// or as a map with a repository and an optional registry and tag.
+func (r *imageRelocation) relocateValue(value interface{}) (interface{}, bool) {
+	switch v := value.(type) {
+	case string:
+		if target, ok := r.names[parseImageReference(v).name()]; ok && !r.relocated(v) {
+			return target, true
+		}
+	case []interface{}:
+		changed := false
+		for i := range v {
+			relocated, ok := r.relocateValue(v[i])
+			v[i], changed = relocated, changed || ok
+		}
+		return v, changed
+	case map[string]interface{}:
+		changed := r.relocateImageMap(v)
+		for key := range v {
+			if changed && (key == "repository" || key == "registry") {
+				continue
+			}
+			relocated, ok := r.relocateValue(v[key])
+			v[key], changed = relocated, changed || ok
+		}
+		return v, changed
+	}
+	return value, false
+}
+
+func (r *imageRelocation) relocateImageMap(values map[string]interface{}) bool {
+	repository, ok := values["repository"].(string)
+	if !ok || r.relocated(repository) {
+		return false
+	}
+	image := parseImageReference(repository)
+	if !r.repositories[image.repository] {
+		return false
+	}
+	if _, ok := values["registry"]; ok {
+		values["registry"] = r.prefix
+		values["repository"] = image.repository
+	} else {
+		values["repository"] = r.prefix + "/" + image.repository
+	}
+	return true
+}
+
+// relocateTemplates replaces images written as is in the templates of chrt and
+// of its dependencies, on the image: lines extractImages finds them on.
+func (r *imageRelocation) relocateTemplates(chrt *chart.Chart, images []string) {
+	for _, ref := range images {
+		target, ok := r.targets[ref]
+		if !ok {
+			continue
+		}
+		matcher := regexp.MustCompile(`(?m)^(\s*image:\s*["']?)` + regexp.QuoteMeta(ref) + `(["']?\s*)$`)
+		r.replaceInTemplates(chrt, matcher, "${1}"+target+"${2}")
+	}
+}
+
+func (r *imageRelocation) replaceInTemplates(chrt *chart.Chart, matcher *regexp.Regexp, replacement string) {
+	for _, template := range chrt.Templates {
+		template.Data = matcher.ReplaceAll(template.Data, []byte(replacement))
+	}
+	for _, dep := range chrt.Dependencies {
+		r.replaceInTemplates(dep, matcher, replacement)
+	}
+}
+
+// replaceChartArtifact puts the chart archive at archivePath, as in repo/path,
+// in place of the archive of artifacts created from source.
+func replaceChartArtifact(artifacts []bundleArtifact, source, archivePath string) []bundleArtifact {
+	replaced := make([]bundleArtifact, 0)
+	for _, artifact := range artifacts {
+		if artifact.source == source {
+			artifact = bundleArtifact{
+				name:        archivePath[strings.LastIndex(archivePath, "/")+1:],
+				packageType: "helm",
+				source:      archivePath,
+				origins:     artifact.origins,
+				patterns:    []string{archivePath},
+			}
+		}
+		replaced = append(replaced, artifact)
+	}
+	return replaced
+}
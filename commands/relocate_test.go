@@ -0,0 +1,156 @@
+package commands
+
+import (
+	"io/ioutil"
+	"k8s.io/helm/pkg/chartutil"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRelocateChart(t *testing.T) {
+	chrt, err := chartutil.Load("testdata/artifactory-jcr-2.2.0.tgz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	remaining, err := relocateChart(chrt, "edge.example.com/docker-edge/", "2.2.0-relocated")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(remaining) != 0 {
+		t.Errorf("Expected all images to be relocated, got %v", remaining)
+	}
+
+	dir, err := ioutil.TempDir("", "relocate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	archive, err := chartutil.Save(chrt, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(archive, "artifactory-jcr-2.2.0-relocated.tgz") {
+		t.Error("Unexpected relocated chart archive " + archive)
+	}
+	relocated, err := chartutil.Load(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	images, err := renderImages(relocated)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"edge.example.com/docker-edge/alpine:3.10",
+		"edge.example.com/docker-edge/bitnami/postgresql:9.6.17-debian-10-r21",
+		"edge.example.com/docker-edge/jfrog/artifactory-jcr:7.4.1",
+		"edge.example.com/docker-edge/jfrog/nginx-artifactory-pro:7.4.1",
+	}
+	if actual := sortedKeys(images); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected images:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(actual, "\n"))
+	}
+
+	// Images which the chart doesn't use are left as they are.
+	values := relocated.Dependencies[0].Values.Raw
+	if !strings.Contains(values, "repository: docker.bintray.io/jfrog/artifactory-pro") ||
+		!strings.Contains(values, "repository: docker.elastic.co/beats/filebeat") {
+		t.Errorf("Unexpected relocated values:\n%s", values)
+	}
+}
+
+func TestRelocateChartTemplates(t *testing.T) {
+	chrt := &chart.Chart{
+		Metadata: &chart.Metadata{Name: "app", Version: "1.0.0"},
+		Values:   &chart.Config{Raw: "# Values without images\nreplicas: 1\n"},
+		Templates: []*chart.Template{{
+			Name: "templates/deployment.yaml",
+			Data: []byte("spec:\n  containers:\n  - name: etcd\n    image: \"quay.io/coreos/etcd:v3.4.13\"\n    args: [\"--image=quay.io/coreos/etcd:v3.4.130\"]\n  - name: busybox\n    image: busybox\n"),
+		}},
+	}
+	remaining, err := relocateChart(chrt, "edge.example.com", "1.0.0-edge")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(remaining) != 0 {
+		t.Errorf("Expected all images to be relocated, got %v", remaining)
+	}
+	expected := "spec:\n  containers:\n  - name: etcd\n    image: \"edge.example.com/coreos/etcd:v3.4.13\"\n    args: [\"--image=quay.io/coreos/etcd:v3.4.130\"]\n  - name: busybox\n    image: edge.example.com/busybox:latest\n"
+	if string(chrt.Templates[0].Data) != expected {
+		t.Errorf("Expected template:\n%s\ngot:\n%s", expected, chrt.Templates[0].Data)
+	}
+	if chrt.Values.Raw != "# Values without images\nreplicas: 1\n" {
+		t.Errorf("Expected values without images to be left as they are, got:\n%s", chrt.Values.Raw)
+	}
+	if chrt.Metadata.Version != "1.0.0-edge" {
+		t.Error("Unexpected relocated version " + chrt.Metadata.Version)
+	}
+}
+
+func TestRelocateRenderedChart(t *testing.T) {
+	chrt, err := chartutil.LoadFiles([]*chartutil.BufferedFile{
+		{Name: "Chart.yaml", Data: []byte("name: app\nversion: 1.0.0\n")},
+		{Name: "values.yaml", Data: []byte("# The image of the app\nimage:\n  repository: nginx\n  tag: \"1.19\"\ndb:\n  enabled: false\n")},
+		{Name: "requirements.yaml", Data: []byte("dependencies:\n- name: db\n  version: 1.0.0\n  condition: db.enabled\n")},
+		{Name: "templates/deployment.yaml", Data: []byte("image: {{ .Values.image.repository }}:{{ .Values.image.tag }}\n")},
+		{Name: "charts/db/Chart.yaml", Data: []byte("name: db\nversion: 1.0.0\n")},
+		{Name: "charts/db/values.yaml", Data: []byte("image: postgres:11\n")},
+		{Name: "charts/db/templates/statefulset.yaml", Data: []byte("image: {{ .Values.image }}\n")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// As from-chart does, the chart is relocated after it's been rendered to
+	// create the file spec, from a copy.
+	source := copyChart(chrt)
+	if _, _, err := createFilespec(chrt, "helm-local", "docker-local"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := relocateChart(source, "edge.example.com", "1.0.0-edge"); err != nil {
+		t.Fatal(err)
+	}
+
+	dir, err := ioutil.TempDir("", "relocate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	archive, err := chartutil.Save(source, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	relocated, err := chartutil.Load(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(relocated.Dependencies) != 1 || relocated.Dependencies[0].Values.Raw != "image: postgres:11\n" {
+		t.Errorf("Expected the disabled dependency to be left as it is, got %+v", relocated.Dependencies)
+	}
+	values, err := chartutil.ReadValues([]byte(relocated.Values.Raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{
+		"image": map[string]interface{}{"repository": "edge.example.com/nginx", "tag": "1.19"},
+		"db":    map[string]interface{}{"enabled": false},
+	}
+	if !reflect.DeepEqual(map[string]interface{}(values), expected) {
+		t.Errorf("Expected only the image of the values to be relocated, got:\n%s", relocated.Values.Raw)
+	}
+}
+
+func TestReplaceChartArtifact(t *testing.T) {
+	origins := []artifactOrigin{{chart: "app", valuesProfile: defaultValuesProfile}}
+	artifacts := []bundleArtifact{
+		{name: "alpine:3.10", packageType: "docker", source: "alpine:3.10", patterns: []string{"docker-local/alpine/3.10/"}},
+		{name: "app-1.0.0.tgz", packageType: "helm", source: "helm-local/app-1.0.0.tgz", origins: origins, patterns: []string{"helm-local/app-1.0.0.tgz"}},
+	}
+	replaced := replaceChartArtifact(artifacts, "helm-local/app-1.0.0.tgz", "helm-local/apps/app-1.0.0-relocated.tgz")
+	expected := bundleArtifact{name: "app-1.0.0-relocated.tgz", packageType: "helm", source: "helm-local/apps/app-1.0.0-relocated.tgz",
+		origins: origins, patterns: []string{"helm-local/apps/app-1.0.0-relocated.tgz"}}
+	if len(replaced) != 2 || !reflect.DeepEqual(replaced[0], artifacts[0]) || !reflect.DeepEqual(replaced[1], expected) {
+		t.Errorf("Unexpected artifacts %+v", replaced)
+	}
+}
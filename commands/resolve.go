@@ -0,0 +1,111 @@
+package commands
+
+import (
+	"github.com/jfrog/jfrog-cli-core/artifactory/spec"
+	rtutils "github.com/jfrog/jfrog-cli-core/artifactory/utils"
+	"github.com/jfrog/jfrog-client-go/utils/log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultResolveWorkers = "8"
+
+var (
+	// searchAttempts is how many times a search is tried before giving up, and
+	// searchBackoff the delay before the first retry, doubled for each retry.
+	searchAttempts = 3
+	searchBackoff  = time.Second
+)
+
+// withRetries retries searches which fail, waiting longer after each failure.
+func withRetries(search artifactSearcher) artifactSearcher {
+	return func(specfiles *spec.SpecFiles) ([]rtutils.SearchResult, error) {
+		delay := searchBackoff
+		for attempt := 1; ; attempt++ {
+			results, err := search(specfiles)
+			if err == nil || attempt >= searchAttempts {
+				return results, err
+			}
+			log.Warn("Search failed, retrying in " + delay.String() + ": " + err.Error())
+			time.Sleep(delay)
+			delay *= 2
+		}
+	}
+}
+
+// resolveArtifacts searches for each of artifacts, workers at a time, and
+// returns the results without duplicates. Rather than searching whole
+// repositories through the wildcard patterns of an artifact, as in
+// repo/*/alpine/3.10/, its exact patterns are searched first, and the others
+// only if those find nothing.
+func resolveArtifacts(artifacts []bundleArtifact, search artifactSearcher, workers int) ([]rtutils.SearchResult, error) {
+	resolved := make([][]rtutils.SearchResult, len(artifacts))
+	errs := make([]error, len(artifacts))
+	indexes := make(chan int)
+	var progress sync.Mutex
+	done := 0
+	var wg sync.WaitGroup
+	for w := 0; w < workers && w < len(artifacts); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				resolved[i], errs[i] = resolveArtifact(artifacts[i], search)
+				progress.Lock()
+				done++
+				log.Info("Resolved " + strconv.Itoa(done) + " of " + strconv.Itoa(len(artifacts)) + " artifacts.")
+				progress.Unlock()
+			}
+		}()
+	}
+	for i := range artifacts {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+	results := make([]rtutils.SearchResult, 0)
+	seen := map[string]bool{}
+	for i := range artifacts {
+		if errs[i] != nil {
+			return nil, errs[i]
+		}
+		for _, result := range resolved[i] {
+			if !seen[result.Path] {
+				seen[result.Path] = true
+				results = append(results, result)
+			}
+		}
+	}
+	return results, nil
+}
+
+func resolveArtifact(artifact bundleArtifact, search artifactSearcher) ([]rtutils.SearchResult, error) {
+	exact := bundleArtifact{targetRepos: artifact.targetRepos}
+	wildcard := bundleArtifact{targetRepos: artifact.targetRepos}
+	for _, pattern := range artifact.patterns {
+		if strings.Contains(pattern, "/*/") {
+			wildcard.patterns = append(wildcard.patterns, pattern)
+		} else {
+			exact.patterns = append(exact.patterns, pattern)
+		}
+	}
+	others := bundleArtifact{files: artifact.files, targetRepos: artifact.targetRepos}
+	results := make([]rtutils.SearchResult, 0)
+	for _, entries := range []bundleArtifact{exact, wildcard, others} {
+		if len(entries.patterns) == 0 && len(entries.files) == 0 {
+			continue
+		}
+		if len(results) > 0 && len(entries.patterns) > 0 {
+			// The exact patterns found the artifact, the wildcard ones needn't be searched.
+			continue
+		}
+		found, err := search(&spec.SpecFiles{Files: entries.specFiles()})
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, found...)
+	}
+	return results, nil
+}
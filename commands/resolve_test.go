@@ -0,0 +1,85 @@
+package commands
+
+import (
+	"errors"
+	"github.com/jfrog/jfrog-cli-core/artifactory/spec"
+	rtutils "github.com/jfrog/jfrog-cli-core/artifactory/utils"
+	"github.com/jfrog/jfrog-client-go/artifactory/services/utils"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestResolveArtifacts(t *testing.T) {
+	stored := []string{
+		"docker-local/alpine/3.10/manifest.json",
+		"docker-local/library/busybox/1.31/manifest.json",
+		"helm-local/app-1.0.0.tgz",
+		"generic-local/installer.sh",
+	}
+	var mutex sync.Mutex
+	searched := make([]string, 0)
+	search := func(specfiles *spec.SpecFiles) ([]rtutils.SearchResult, error) {
+		results := make([]rtutils.SearchResult, 0)
+		for _, file := range specfiles.Files {
+			mutex.Lock()
+			searched = append(searched, file.Pattern+file.Aql.ItemsFind)
+			mutex.Unlock()
+			for _, path := range stored {
+				if file.Aql.ItemsFind != "" && strings.HasSuffix(path, ".sh") || file.Pattern != "" && patternToRegexp(file.Pattern).MatchString(path) {
+					results = append(results, rtutils.SearchResult{Path: path})
+				}
+			}
+		}
+		return results, nil
+	}
+	artifacts := []bundleArtifact{
+		newBundleArtifact("alpine:3.10", "docker-local", "alpine/3.10/"),
+		newBundleArtifact("busybox:1.31", "docker-local", "busybox/1.31/"),
+		newBundleArtifact("app-1.0.0.tgz", "helm-local", "app-1.0.0.tgz"),
+		newBundleArtifact("app-1.0.0.tgz", "helm-local", "app-1.0.0.tgz"),
+		{name: "installers", files: []spec.File{{Aql: utils.Aql{ItemsFind: `{"name":{"$match":"*.sh"}}`}}}},
+	}
+	results, err := resolveArtifacts(artifacts, search, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if paths := resultPaths(results); !reflect.DeepEqual(paths, []string{stored[0], stored[1], stored[2], stored[3]}) {
+		t.Errorf("Unexpected results %v", paths)
+	}
+	sort.Strings(searched)
+	expected := []string{
+		"docker-local/*/busybox/1.31/",
+		"docker-local/alpine/3.10/",
+		"docker-local/busybox/1.31/",
+		"helm-local/app-1.0.0.tgz",
+		"helm-local/app-1.0.0.tgz",
+		`{"name":{"$match":"*.sh"}}`,
+	}
+	if !reflect.DeepEqual(searched, expected) {
+		t.Errorf("Expected searches:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(searched, "\n"))
+	}
+}
+
+func TestWithRetries(t *testing.T) {
+	defer func(backoff time.Duration) { searchBackoff = backoff }(searchBackoff)
+	searchBackoff = time.Millisecond
+	attempts := 0
+	search := withRetries(func(specfiles *spec.SpecFiles) ([]rtutils.SearchResult, error) {
+		attempts++
+		if attempts < 3 {
+			return nil, errors.New("502 Bad Gateway")
+		}
+		return []rtutils.SearchResult{{Path: "helm-local/app-1.0.0.tgz"}}, nil
+	})
+	if results, err := search(&spec.SpecFiles{}); err != nil || len(results) != 1 || attempts != 3 {
+		t.Errorf("Expected the third attempt to succeed, got %v, %v after %d attempts", results, err, attempts)
+	}
+	attempts = -10
+	if _, err := search(&spec.SpecFiles{}); err == nil || attempts != -7 {
+		t.Errorf("Expected the search to fail after 3 attempts, got %v after %d attempts", err, attempts+10)
+	}
+}
@@ -0,0 +1,221 @@
+package commands
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"github.com/jfrog/jfrog-cli-core/utils/config"
+	"github.com/jfrog/jfrog-client-go/utils/errorutils"
+	"github.com/jfrog/jfrog-client-go/utils/log"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+const (
+	sbomSuffix      = "-sbom.cdx.json"
+	sbomBundleRef   = "bundle"
+	cycloneDxSpec   = "1.4"
+	cycloneDxFormat = "CycloneDX"
+)
+
+// cycloneDxBom is a CycloneDX JSON document.
+type cycloneDxBom struct {
+	BomFormat    string                `json:"bomFormat"`
+	SpecVersion  string                `json:"specVersion"`
+	SerialNumber string                `json:"serialNumber"`
+	Version      int                   `json:"version"`
+	Metadata     cycloneDxMetadata     `json:"metadata"`
+	Components   []cycloneDxComponent  `json:"components"`
+	Dependencies []cycloneDxDependency `json:"dependencies"`
+}
+
+type cycloneDxMetadata struct {
+	Timestamp string             `json:"timestamp"`
+	Component cycloneDxComponent `json:"component"`
+}
+
+type cycloneDxComponent struct {
+	BomRef             string                       `json:"bom-ref"`
+	Type               string                       `json:"type"`
+	Name               string                       `json:"name"`
+	Version            string                       `json:"version,omitempty"`
+	Purl               string                       `json:"purl,omitempty"`
+	Hashes             []cycloneDxHash              `json:"hashes,omitempty"`
+	ExternalReferences []cycloneDxExternalReference `json:"externalReferences,omitempty"`
+}
+
+type cycloneDxHash struct {
+	Alg     string `json:"alg"`
+	Content string `json:"content"`
+}
+
+type cycloneDxExternalReference struct {
+	Type string `json:"type"`
+	Url  string `json:"url"`
+}
+
+type cycloneDxDependency struct {
+	Ref       string   `json:"ref"`
+	DependsOn []string `json:"dependsOn"`
+}
+
+// createSbom creates a CycloneDX document describing the release bundle name
+// version: the chart archives among artifacts, whose chart was read, with the
+// Helm repository they come from, the images, with the digests they're pinned
+// to, and which chart requires which chart or uses which image, from the
+// origins of artifacts. Artifacts which no chart requires depend on the bundle.
+func createSbom(name, version string, artifacts []bundleArtifact, digests map[string]string, timestamp time.Time) ([]byte, error) {
+	bom := cycloneDxBom{
+		BomFormat:   cycloneDxFormat,
+		SpecVersion: cycloneDxSpec,
+		Version:     1,
+		Metadata: cycloneDxMetadata{
+			Timestamp: timestamp.UTC().Format(time.RFC3339),
+			Component: cycloneDxComponent{BomRef: sbomBundleRef, Type: "application", Name: name, Version: version},
+		},
+		Components: make([]cycloneDxComponent, 0),
+	}
+	serial := make([]byte, 16)
+	if _, err := rand.Read(serial); err != nil {
+		return nil, errorutils.CheckError(err)
+	}
+	serial[6], serial[8] = serial[6]&0x0f|0x40, serial[8]&0x3f|0x80
+	bom.SerialNumber = fmt.Sprintf("urn:uuid:%x-%x-%x-%x-%x", serial[0:4], serial[4:6], serial[6:8], serial[8:10], serial[10:])
+
+	// The charts, by name, and the references their dependencies are added to.
+	charts := map[string]string{}
+	dependsOn := map[string][]string{sbomBundleRef: {}}
+	refs := []string{sbomBundleRef}
+	for _, artifact := range artifacts {
+		if origin, ok := chartArchiveOrigin(artifact); ok {
+			charts[origin.chart] = "chart:" + artifact.source
+		}
+	}
+	addDependency := func(parent, ref string) {
+		if _, ok := dependsOn[parent]; !ok {
+			dependsOn[parent] = make([]string, 0)
+			refs = append(refs, parent)
+		}
+		if !containsString(dependsOn[parent], ref) {
+			dependsOn[parent] = append(dependsOn[parent], ref)
+		}
+	}
+	for _, artifact := range artifacts {
+		component, parents := sbomComponent(artifact, digests, charts)
+		if component == nil {
+			continue
+		}
+		bom.Components = append(bom.Components, *component)
+		if _, ok := dependsOn[component.BomRef]; !ok {
+			dependsOn[component.BomRef] = make([]string, 0)
+			refs = append(refs, component.BomRef)
+		}
+		if len(parents) == 0 {
+			parents = []string{sbomBundleRef}
+		}
+		for _, parent := range parents {
+			addDependency(parent, component.BomRef)
+		}
+	}
+	for _, ref := range refs {
+		bom.Dependencies = append(bom.Dependencies, cycloneDxDependency{Ref: ref, DependsOn: dependsOn[ref]})
+	}
+	content, err := json.MarshalIndent(bom, "", "  ")
+	return content, errorutils.CheckError(err)
+}
+
+// sbomComponent describes a chart archive or an image, and returns the
+// references of the charts requiring it or using it, among charts.
+func sbomComponent(artifact bundleArtifact, digests map[string]string, charts map[string]string) (*cycloneDxComponent, []string) {
+	parents := make([]string, 0)
+	switch {
+	case artifact.packageType == "helm":
+		origin, ok := chartArchiveOrigin(artifact)
+		if !ok {
+			return nil, nil
+		}
+		chartVersion := strings.TrimSuffix(strings.TrimPrefix(artifact.name, origin.chart+"-"), ".tgz")
+		component := &cycloneDxComponent{
+			BomRef:             "chart:" + artifact.source,
+			Type:               "application",
+			Name:               origin.chart,
+			Version:            chartVersion,
+			Purl:               "pkg:helm/" + origin.chart + "@" + chartVersion,
+			ExternalReferences: []cycloneDxExternalReference{{Type: "distribution", Url: artifact.source}},
+		}
+		for _, o := range artifact.origins {
+			if parent, ok := charts[o.parentChart]; ok && !containsString(parents, parent) {
+				parents = append(parents, parent)
+			}
+		}
+		return component, parents
+	case artifact.packageType == "docker" && artifact.source != "":
+		image := parseImageReference(artifact.source)
+		component := &cycloneDxComponent{BomRef: "image:" + artifact.source, Type: "container", Name: image.repository}
+		qualifiers := url.Values{}
+		if image.registry != "" {
+			qualifiers.Set("repository_url", image.registry)
+		}
+		purlVersion := image.digest
+		if image.tag != "" {
+			component.Version = image.tag
+			qualifiers.Set("tag", image.tag)
+		}
+		if digest, ok := digests[artifact.name]; ok {
+			purlVersion = digest
+			component.Hashes = []cycloneDxHash{{Alg: "SHA-256", Content: strings.TrimPrefix(digest, "sha256:")}}
+		}
+		if purlVersion == "" {
+			purlVersion = image.tag
+		}
+		component.Purl = "pkg:docker/" + image.repository + "@" + strings.ReplaceAll(url.PathEscape(purlVersion), ":", "%3A")
+		if len(qualifiers) > 0 {
+			component.Purl = component.Purl + "?" + qualifiers.Encode()
+		}
+		for _, o := range artifact.origins {
+			if parent, ok := charts[o.chart]; ok && !containsString(parents, parent) {
+				parents = append(parents, parent)
+			}
+		}
+		return component, parents
+	}
+	return nil, nil
+}
+
+// uploadSbom uploads the SBOM of the release bundle to uploadPath, or to a
+// file named after the bundle if uploadPath is a repository or a folder, and
+// returns it as an artifact of the bundle.
+func uploadSbom(rtDetails *config.ArtifactoryDetails, name, version string, artifacts []bundleArtifact, digests map[string]string, uploadPath string, dryRun bool) (bundleArtifact, error) {
+	content, err := createSbom(name, version, artifacts, digests, time.Now())
+	if err != nil {
+		return bundleArtifact{}, err
+	}
+	uploadPath = strings.TrimPrefix(uploadPath, "/")
+	if !strings.Contains(uploadPath, "/") || strings.HasSuffix(uploadPath, "/") {
+		uploadPath = path.Join(uploadPath, name+"-"+version+sbomSuffix)
+	}
+	if dryRun {
+		log.Info("Dry run: the SBOM would be uploaded to " + uploadPath + ":\n" + string(content))
+	} else {
+		file, err := ioutil.TempFile("", "sbom")
+		if err != nil {
+			return bundleArtifact{}, errorutils.CheckError(err)
+		}
+		defer os.Remove(file.Name())
+		_, err = file.Write(content)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return bundleArtifact{}, errorutils.CheckError(err)
+		}
+		if err := uploadFileToArtifactory(rtDetails, file.Name(), uploadPath); err != nil {
+			return bundleArtifact{}, err
+		}
+	}
+	return bundleArtifact{name: path.Base(uploadPath), packageType: "generic", source: uploadPath, patterns: []string{uploadPath}}, nil
+}
@@ -0,0 +1,71 @@
+package commands
+
+import (
+	"encoding/json"
+	"k8s.io/helm/pkg/chartutil"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCreateSbom(t *testing.T) {
+	chrt, err := chartutil.Load("testdata/artifactory-jcr-2.2.0.tgz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, artifacts, err := createFilespec(chrt, "helm-local", "docker-local")
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest := "sha256:" + strings.Repeat("a", 64)
+	content, err := createSbom("platform", "1.0.0", artifacts, map[string]string{"alpine:3.10": digest}, time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bom := &cycloneDxBom{}
+	if err := json.Unmarshal(content, bom); err != nil {
+		t.Fatal(err)
+	}
+	if bom.BomFormat != "CycloneDX" || !strings.HasPrefix(bom.SerialNumber, "urn:uuid:") || bom.Metadata.Timestamp != "2020-06-01T00:00:00Z" ||
+		bom.Metadata.Component.Name != "platform" || bom.Metadata.Component.Version != "1.0.0" {
+		t.Errorf("Unexpected document:\n%s", content)
+	}
+	components := map[string]cycloneDxComponent{}
+	for _, component := range bom.Components {
+		components[component.BomRef] = component
+	}
+	jcr := components["chart:helm-local/artifactory-jcr-2.2.0.tgz"]
+	if jcr.Name != "artifactory-jcr" || jcr.Version != "2.2.0" || jcr.Purl != "pkg:helm/artifactory-jcr@2.2.0" ||
+		len(jcr.ExternalReferences) != 1 || jcr.ExternalReferences[0].Url != "helm-local/artifactory-jcr-2.2.0.tgz" {
+		t.Errorf("Unexpected chart %+v", jcr)
+	}
+	alpine := components["image:alpine:3.10"]
+	if alpine.Type != "container" || alpine.Purl != "pkg:docker/alpine@sha256%3A"+strings.Repeat("a", 64)+"?tag=3.10" ||
+		len(alpine.Hashes) != 1 || alpine.Hashes[0].Content != strings.Repeat("a", 64) {
+		t.Errorf("Unexpected image %+v", alpine)
+	}
+	if postgresql := components["image:docker.bintray.io/bitnami/postgresql:9.6.17-debian-10-r21"]; postgresql.Purl !=
+		"pkg:docker/bitnami/postgresql@9.6.17-debian-10-r21?repository_url=docker.bintray.io&tag=9.6.17-debian-10-r21" {
+		t.Errorf("Unexpected image %+v", postgresql)
+	}
+
+	dependencies := map[string][]string{}
+	for _, dependency := range bom.Dependencies {
+		dependencies[dependency.Ref] = dependency.DependsOn
+	}
+	expected := map[string][]string{
+		"bundle": {"chart:helm-local/artifactory-jcr-2.2.0.tgz"},
+		"chart:helm-local/artifactory-jcr-2.2.0.tgz": {"chart:helm-local/artifactory-9.4.0.tgz"},
+		"chart:helm-local/postgresql-8.7.3.tgz":      {"image:docker.bintray.io/bitnami/postgresql:9.6.17-debian-10-r21"},
+	}
+	for ref, dependsOn := range expected {
+		if !reflect.DeepEqual(dependencies[ref], dependsOn) {
+			t.Errorf("Expected %s to depend on %v, got %v", ref, dependsOn, dependencies[ref])
+		}
+	}
+	artifactory := dependencies["chart:helm-local/artifactory-9.4.0.tgz"]
+	if !containsString(artifactory, "chart:helm-local/postgresql-8.7.3.tgz") || !containsString(artifactory, "image:alpine:3.10") {
+		t.Errorf("Expected artifactory to depend on postgresql and alpine, got %v", artifactory)
+	}
+}
@@ -0,0 +1,273 @@
+package commands
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"github.com/jfrog/jfrog-cli-core/artifactory/spec"
+	"github.com/jfrog/jfrog-client-go/utils/errorutils"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"path"
+	"strings"
+)
+
+const (
+	cosignSignatureAnnotation   = "dev.cosignproject.cosign/signature"
+	cosignCertificateAnnotation = "dev.sigstore.cosign/certificate"
+	cosignChainAnnotation       = "dev.sigstore.cosign/chain"
+)
+
+var (
+	// Fulcio certificates carry the issuer of the OIDC token they were issued
+	// for, as a raw string in the original extension and as a DER UTF8String
+	// in its replacement.
+	fulcioIssuerOid   = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 1}
+	fulcioIssuerV2Oid = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 8}
+)
+
+// signatureVerifier verifies cosign signatures offline, either with a public
+// key or with the certificate of a keyless signature, which must chain to
+// roots and be issued to identity by issuer.
+type signatureVerifier struct {
+	publicKey crypto.PublicKey
+	identity  string
+	issuer    string
+	roots     *x509.CertPool
+}
+
+// simpleSigningPayload is the payload cosign signs, binding the signature to
+// the digest of the image.
+type simpleSigningPayload struct {
+	Critical struct {
+		Image struct {
+			DockerManifestDigest string `json:"docker-manifest-digest"`
+		} `json:"image"`
+		Type string `json:"type"`
+	} `json:"critical"`
+}
+
+// signatureCheck is the result of verifying the signatures of an image.
+type signatureCheck struct {
+	name string
+	err  error
+}
+
+// newSignatureVerifier creates a verifier with the public key in the PEM file
+// keyPath, or, for keyless signatures, with the certificates in the PEM file
+// rootsPath and the identity and OIDC issuer of the signer.
+func newSignatureVerifier(keyPath, rootsPath, identity, issuer string) (*signatureVerifier, error) {
+	if keyPath != "" {
+		content, err := ioutil.ReadFile(keyPath)
+		if err != nil {
+			return nil, errorutils.CheckError(err)
+		}
+		block, _ := pem.Decode(content)
+		if block == nil {
+			return nil, errorutils.CheckError(errors.New("No PEM encoded public key found in " + keyPath + "."))
+		}
+		publicKey, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, errorutils.CheckError(err)
+		}
+		return &signatureVerifier{publicKey: publicKey}, nil
+	}
+	if rootsPath == "" || identity == "" || issuer == "" {
+		return nil, errorutils.CheckError(errors.New("Verifying signatures requires --signature-key, or --certificate-roots, --certificate-identity and --certificate-oidc-issuer."))
+	}
+	content, err := ioutil.ReadFile(rootsPath)
+	if err != nil {
+		return nil, errorutils.CheckError(err)
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(content) {
+		return nil, errorutils.CheckError(errors.New("No PEM encoded certificate found in " + rootsPath + "."))
+	}
+	return &signatureVerifier{identity: identity, issuer: issuer, roots: roots}, nil
+}
+
+// verifyImageSignatures verifies the cosign signatures of the images among
+// artifacts which have a digest, stored as a sha256-<hex>.sig tag next to the
+// tag folder of the image, whose manifest is among paths. An image is verified
+// if one of its signatures is valid and signs its digest.
+func verifyImageSignatures(artifacts []bundleArtifact, paths []string, digests map[string]string, search artifactSearcher, read artifactReader, verifier *signatureVerifier) ([]signatureCheck, error) {
+	checks := make([]signatureCheck, 0)
+	for _, artifact := range artifacts {
+		digest, ok := digests[artifact.name]
+		if !ok || artifact.packageType != "docker" {
+			continue
+		}
+		manifests := tagManifests(artifact.matches(paths))
+		if len(manifests) == 0 {
+			continue
+		}
+		folder := path.Dir(path.Dir(manifests[0])) + "/" + strings.Replace(digest, ":", "-", 1) + ".sig/"
+		results, err := search(&spec.SpecFiles{Files: []spec.File{{Pattern: folder + dockerManifestFile}}})
+		if err != nil {
+			return nil, err
+		}
+		if len(results) == 0 {
+			checks = append(checks, signatureCheck{name: artifact.name, err: errors.New("no signature found")})
+			continue
+		}
+		content, err := readArtifact(read, results[0].Path)
+		if err != nil {
+			return nil, err
+		}
+		manifest := &imageManifest{}
+		if err := json.Unmarshal(content, manifest); err != nil {
+			return nil, errorutils.CheckError(errors.New("Failed to parse the signature manifest " + results[0].Path + ": " + err.Error()))
+		}
+		check := signatureCheck{name: artifact.name, err: errors.New("no signature layer found")}
+		for _, layer := range manifest.Layers {
+			signature, ok := layer.Annotations[cosignSignatureAnnotation]
+			if !ok {
+				continue
+			}
+			payload, err := readArtifact(read, folder+strings.Replace(layer.Digest, ":", "__", 1))
+			if err != nil {
+				return nil, err
+			}
+			if sha256Digest(payload) != layer.Digest {
+				check.err = errors.New("the signed payload doesn't match its digest")
+				continue
+			}
+			check.err = verifier.verify(payload, signature, layer.Annotations[cosignCertificateAnnotation], layer.Annotations[cosignChainAnnotation], digest)
+			if check.err == nil {
+				break
+			}
+		}
+		checks = append(checks, check)
+	}
+	return checks, nil
+}
+
+// verify checks that signature, base64 encoded, signs payload, and that
+// payload is a cosign signature of digest. Keyless signatures are verified
+// with the PEM encoded certificate, which must be valid at the time it was
+// issued, since the transparency log recording the time of signing can't be
+// reached offline.
+func (sv *signatureVerifier) verify(payload []byte, signature, certificate, chain, digest string) error {
+	decoded, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return errors.New("invalid signature encoding")
+	}
+	publicKey := sv.publicKey
+	if publicKey == nil {
+		cert, err := sv.verifyCertificate(certificate, chain)
+		if err != nil {
+			return err
+		}
+		publicKey = cert.PublicKey
+	}
+	if err := verifySignature(publicKey, payload, decoded); err != nil {
+		return err
+	}
+	signed := &simpleSigningPayload{}
+	if err := json.Unmarshal(payload, signed); err != nil {
+		return errors.New("invalid signed payload")
+	}
+	if signed.Critical.Image.DockerManifestDigest != digest {
+		return errors.New("the signature is for " + signed.Critical.Image.DockerManifestDigest)
+	}
+	return nil
+}
+
+func (sv *signatureVerifier) verifyCertificate(certificate, chain string) (*x509.Certificate, error) {
+	block, _ := pem.Decode([]byte(certificate))
+	if block == nil {
+		return nil, errors.New("no certificate found for a keyless signature")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, errors.New("invalid certificate: " + err.Error())
+	}
+	intermediates := x509.NewCertPool()
+	intermediates.AppendCertsFromPEM([]byte(chain))
+	_, err = cert.Verify(x509.VerifyOptions{Roots: sv.roots, Intermediates: intermediates, CurrentTime: cert.NotBefore,
+		KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning}})
+	if err != nil {
+		return nil, errors.New("untrusted certificate: " + err.Error())
+	}
+	identities := append([]string{}, cert.EmailAddresses...)
+	for _, uri := range cert.URIs {
+		identities = append(identities, uri.String())
+	}
+	if !containsString(identities, sv.identity) {
+		return nil, errors.New("the certificate is issued to " + strings.Join(identities, ", "))
+	}
+	if issuer := certificateIssuer(cert); issuer != sv.issuer {
+		return nil, errors.New("the certificate is issued by " + issuer)
+	}
+	return cert, nil
+}
+
+func certificateIssuer(cert *x509.Certificate) string {
+	for _, extension := range cert.Extensions {
+		if extension.Id.Equal(fulcioIssuerV2Oid) {
+			var issuer string
+			if _, err := asn1.Unmarshal(extension.Value, &issuer); err == nil {
+				return issuer
+			}
+		}
+	}
+	for _, extension := range cert.Extensions {
+		if extension.Id.Equal(fulcioIssuerOid) {
+			return string(extension.Value)
+		}
+	}
+	return ""
+}
+
+func verifySignature(publicKey crypto.PublicKey, payload, signature []byte) error {
+	hash := sha256.Sum256(payload)
+	switch key := publicKey.(type) {
+	case *ecdsa.PublicKey:
+		var sig struct {
+			R, S *big.Int
+		}
+		if _, err := asn1.Unmarshal(signature, &sig); err != nil || !ecdsa.Verify(key, hash[:], sig.R, sig.S) {
+			return errors.New("invalid signature")
+		}
+	case *rsa.PublicKey:
+		if rsa.VerifyPKCS1v15(key, crypto.SHA256, hash[:], signature) != nil {
+			return errors.New("invalid signature")
+		}
+	case ed25519.PublicKey:
+		if !ed25519.Verify(key, payload, signature) {
+			return errors.New("invalid signature")
+		}
+	default:
+		return errors.New("unsupported public key type")
+	}
+	return nil
+}
+
+// reportSignatures prints the result of verifying the signatures of images,
+// and fails if any of them couldn't be verified.
+func reportSignatures(out io.Writer, checks []signatureCheck) error {
+	fmt.Fprintln(out, "Signatures:")
+	failed := make([]string, 0)
+	for _, check := range checks {
+		if check.err != nil {
+			fmt.Fprintln(out, "- "+check.name+": "+check.err.Error())
+			failed = append(failed, check.name)
+		} else {
+			fmt.Fprintln(out, "- "+check.name+": verified")
+		}
+	}
+	if len(failed) > 0 {
+		return errorutils.CheckError(errors.New("The signatures of " + strings.Join(failed, ", ") + " couldn't be verified."))
+	}
+	return nil
+}
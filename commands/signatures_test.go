@@ -0,0 +1,214 @@
+package commands
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"github.com/jfrog/jfrog-cli-core/artifactory/spec"
+	rtutils "github.com/jfrog/jfrog-cli-core/artifactory/utils"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// signatureFiles returns the files of a cosign signature of digest, signed by
+// key, stored in the sha256-<hex>.sig tag of the image in imageFolder.
+func signatureFiles(t *testing.T, imageFolder, digest string, key *ecdsa.PrivateKey, annotations map[string]string) map[string][]byte {
+	payload := []byte(`{"critical":{"identity":{"docker-reference":"alpine"},"image":{"docker-manifest-digest":"` + digest + `"},"type":"cosign container image signature"},"optional":null}`)
+	hash := sha256.Sum256(payload)
+	signature, err := key.Sign(rand.Reader, hash[:], crypto.SHA256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	layerAnnotations := map[string]string{cosignSignatureAnnotation: base64.StdEncoding.EncodeToString(signature)}
+	for name, value := range annotations {
+		layerAnnotations[name] = value
+	}
+	manifest := imageManifest{
+		SchemaVersion: 2,
+		MediaType:     "application/vnd.oci.image.manifest.v1+json",
+		Layers: []ociDescriptor{{MediaType: "application/vnd.dev.cosign.simplesigning.v1+json", Digest: sha256Digest(payload),
+			Size: int64(len(payload)), Annotations: layerAnnotations}},
+	}
+	content, err := json.Marshal(manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	folder := imageFolder + "/" + strings.Replace(digest, ":", "-", 1) + ".sig/"
+	return map[string][]byte{
+		folder + dockerManifestFile:                                   content,
+		folder + strings.Replace(sha256Digest(payload), ":", "__", 1): payload,
+	}
+}
+
+// checkSignatures verifies the signatures of alpine, signed with signatures,
+// and busybox, which isn't signed.
+func checkSignatures(t *testing.T, verifier *signatureVerifier, signatures func(digest string) map[string][]byte) []signatureCheck {
+	files := imageFiles(t, "docker-local/alpine/3.10", []byte("alpine config"))
+	for p, content := range imageFiles(t, "docker-local/library/busybox/1.31", []byte("busybox config")) {
+		files[p] = content
+	}
+	digests := map[string]string{
+		"alpine:3.10":  sha256Digest(files["docker-local/alpine/3.10/"+dockerManifestFile]),
+		"busybox:1.31": sha256Digest(files["docker-local/library/busybox/1.31/"+dockerManifestFile]),
+	}
+	for p, content := range signatures(digests["alpine:3.10"]) {
+		files[p] = content
+	}
+	read, paths := writeArtifactoryFiles(t, files)
+	search := func(specfiles *spec.SpecFiles) ([]rtutils.SearchResult, error) {
+		results := make([]rtutils.SearchResult, 0)
+		for _, p := range paths {
+			if patternToRegexp(specfiles.Files[0].Pattern).MatchString(p) {
+				results = append(results, rtutils.SearchResult{Path: p})
+			}
+		}
+		return results, nil
+	}
+	artifacts := imageAndChartArtifacts(map[string]string{"alpine:3.10": "alpine:3.10", "busybox:1.31": "busybox:1.31"}, map[string]string{}, "docker-local")
+	checks, err := verifyImageSignatures(artifacts, paths, digests, search, read, verifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(checks) != 2 || checks[0].name != "alpine:3.10" || checks[1].name != "busybox:1.31" {
+		t.Fatalf("Unexpected signature checks %v", checks)
+	}
+	if checks[1].err == nil || checks[1].err.Error() != "no signature found" {
+		t.Errorf("Expected busybox not to be signed, got %v", checks[1].err)
+	}
+	return checks
+}
+
+func TestVerifyImageSignatures(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKey, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir, err := ioutil.TempDir("", "signatures")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	keyPath := filepath.Join(dir, "cosign.pub")
+	if err := ioutil.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKey}), 0644); err != nil {
+		t.Fatal(err)
+	}
+	verifier, err := newSignatureVerifier(keyPath, "", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checks := checkSignatures(t, verifier, func(digest string) map[string][]byte {
+		return signatureFiles(t, "docker-local/alpine", digest, key, nil)
+	})
+	if checks[0].err != nil {
+		t.Errorf("Expected alpine to be verified, got %v", checks[0].err)
+	}
+	if err := reportSignatures(ioutil.Discard, checks); err == nil || !strings.Contains(err.Error(), "busybox:1.31") {
+		t.Errorf("Expected busybox to be blocked, got %v", err)
+	}
+
+	// A signature of another image doesn't sign alpine.
+	checks = checkSignatures(t, verifier, func(digest string) map[string][]byte {
+		files := signatureFiles(t, "docker-local/alpine", "sha256:0123", key, nil)
+		moved := map[string][]byte{}
+		for p, content := range files {
+			moved[strings.Replace(p, "sha256-0123", strings.Replace(digest, ":", "-", 1), 1)] = content
+		}
+		return moved
+	})
+	if checks[0].err == nil || checks[0].err.Error() != "the signature is for sha256:0123" {
+		t.Errorf("Expected the signature of another image to fail, got %v", checks[0].err)
+	}
+
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checks = checkSignatures(t, verifier, func(digest string) map[string][]byte {
+		return signatureFiles(t, "docker-local/alpine", digest, otherKey, nil)
+	})
+	if checks[0].err == nil || checks[0].err.Error() != "invalid signature" {
+		t.Errorf("Expected a signature with another key to fail, got %v", checks[0].err)
+	}
+}
+
+func TestVerifyKeylessImageSignatures(t *testing.T) {
+	rootKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := &x509.Certificate{SerialNumber: big.NewInt(1), Subject: pkix.Name{CommonName: "root"}, IsCA: true, BasicConstraintsValid: true,
+		KeyUsage: x509.KeyUsageCertSign, NotBefore: time.Now().Add(-time.Hour), NotAfter: time.Now().Add(time.Hour)}
+	rootDer, err := x509.CreateCertificate(rand.Reader, root, root, &rootKey.PublicKey, rootKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err = x509.ParseCertificate(rootDer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuer, err := asn1.Marshal("https://token.actions.githubusercontent.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Signing certificates are short lived, and checked at the time they're issued.
+	signingKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf := &x509.Certificate{SerialNumber: big.NewInt(2), EmailAddresses: []string{"builds@example.com"},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning}, KeyUsage: x509.KeyUsageDigitalSignature,
+		NotBefore: time.Now().Add(-30 * time.Minute), NotAfter: time.Now().Add(-20 * time.Minute),
+		ExtraExtensions: []pkix.Extension{{Id: fulcioIssuerV2Oid, Value: issuer}}}
+	leafDer, err := x509.CreateCertificate(rand.Reader, leaf, root, &signingKey.PublicKey, rootKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certificate := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: leafDer}))
+	dir, err := ioutil.TempDir("", "signatures")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	rootsPath := filepath.Join(dir, "roots.pem")
+	if err := ioutil.WriteFile(rootsPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: rootDer}), 0644); err != nil {
+		t.Fatal(err)
+	}
+	signatures := func(digest string) map[string][]byte {
+		return signatureFiles(t, "docker-local/alpine", digest, signingKey, map[string]string{cosignCertificateAnnotation: certificate})
+	}
+
+	verifier, err := newSignatureVerifier("", rootsPath, "builds@example.com", "https://token.actions.githubusercontent.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if checks := checkSignatures(t, verifier, signatures); checks[0].err != nil {
+		t.Errorf("Expected alpine to be verified, got %v", checks[0].err)
+	}
+
+	verifier.identity = "someone@example.com"
+	if checks := checkSignatures(t, verifier, signatures); checks[0].err == nil || checks[0].err.Error() != "the certificate is issued to builds@example.com" {
+		t.Errorf("Expected a certificate issued to someone else to fail, got %v", checks[0].err)
+	}
+
+	if _, err := newSignatureVerifier("", rootsPath, "builds@example.com", ""); err == nil {
+		t.Error("Expected keyless verification without an issuer to fail")
+	}
+}
@@ -1,15 +1,14 @@
 package commands
 
 import (
-	"encoding/json"
 	"errors"
 	"github.com/jfrog/jfrog-cli-core/plugins/components"
 	"github.com/jfrog/jfrog-cli-core/utils/config"
 	"github.com/jfrog/jfrog-cli-core/utils/coreutils"
 	"github.com/jfrog/jfrog-cli-core/artifactory/spec"
-	"github.com/jfrog/jfrog-cli-core/artifactory/commands/distribution"
 	"github.com/jfrog/jfrog-cli-core/artifactory/commands/generic"
 	"github.com/jfrog/jfrog-client-go/utils/errorutils"
+	"github.com/jfrog/jfrog-client-go/utils/log"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -17,6 +16,8 @@ import (
 	"k8s.io/helm/pkg/proto/hapi/chart"
 	"k8s.io/helm/pkg/renderutil"
 	"net/http"
+	"os"
+	"path"
 	"path/filepath"
 	"sort"
 	"strings"
@@ -24,7 +25,9 @@ import (
 	distributionServicesUtils "github.com/jfrog/jfrog-client-go/distribution/services/utils"
 	rthttpclient "github.com/jfrog/jfrog-client-go/artifactory/httpclient"
 	clientutils "github.com/jfrog/jfrog-client-go/utils"
+	rtutils "github.com/jfrog/jfrog-cli-core/artifactory/utils"
 	servicesutils "github.com/jfrog/jfrog-client-go/artifactory/services/utils"
+	"github.com/jfrog/jfrog-client-go/auth"
 )
 
 const (
@@ -35,8 +38,14 @@ const (
 type TranslateChartCommand struct {
 	rtDetails            *config.ArtifactoryDetails
 	releaseBundlesParams distributionServicesUtils.ReleaseBundleParams
+	bundleOptions        bundleOptions
 	sourceChartPath      string
 	dockerRepo           string
+	extendBundle         string
+	relocate             string
+	relocatedVersion     string
+	edgeValues           string
+	edgeValuesPath       string
 	dryRun               bool
 }
 
@@ -68,6 +77,41 @@ func getReleaseBundleTranslateChartArguments() []components.Argument {
 }
 
 func getReleaseBundleTranslateChartFlags() []components.Flag {
+	flags := append(getArtifactoryFlags(),
+		components.StringFlag{
+			Name: "chart-path",
+			Description: "Path to a Helm chart in Artifactory, which should be translated to a release bundle.",
+			Mandatory: true,
+		},
+		components.StringFlag{
+			Name: "docker-repo",
+			Description: "A Docker repository containing all the Docker images the Helm chart requires.",
+			Mandatory: true,
+		},
+		components.StringFlag{
+			Name: "extend",
+			Description: "An existing release bundle, as in name/version, whose artifacts and properties should also be in the new bundle.",
+		},
+		components.StringFlag{
+			Name: "relocate",
+			Description: "A registry, optionally followed by a repository path, such as edge.example.com/docker-edge, to pull the chart's images from on the edge nodes. The chart is repackaged with its images relocated there, uploaded next to the original chart, and put in the release bundle instead of it.",
+		},
+		components.StringFlag{
+			Name: "relocated-version",
+			Description: "The version of the relocated chart. Defaults to the version of the chart followed by -relocated.",
+		},
+		components.StringFlag{
+			Name: "edge-values",
+			Description: "A registry, optionally followed by a repository path, such as edge.example.com/docker-edge, to pull the chart's images from on the edge nodes. A values file setting the images there is uploaded next to the chart, and put in the release bundle.",
+		},
+		components.StringFlag{
+			Name: "edge-values-path",
+			Description: "The path in Artifactory, as in repo/path, to upload the values file of --edge-values to. Defaults to <chart name>-<chart version>-values-edge.yaml next to the chart.",
+		})
+	return append(flags, getReleaseBundleFlags()...)
+}
+
+func getArtifactoryFlags() []components.Flag {
 	return []components.Flag{
 		components.StringFlag{
 			Name:  "url",
@@ -105,16 +149,12 @@ func getReleaseBundleTranslateChartFlags() []components.Flag {
 			Name:  "server-id",
 			Description: "Artifactory server ID configured using the config command.",
 		},
-		components.StringFlag{
-			Name: "chart-path",
-			Description: "Path to a Helm chart in Artifactory, which should be translated to a release bundle.",
-			Mandatory: true,
-		},
-		components.StringFlag{
-			Name: "docker-repo",
-			Description: "A Docker repository containing all the Docker images the Helm chart requires.",
-			Mandatory: true,
-		},
+	}
+}
+
+func getReleaseBundleFlags() []components.Flag {
+	return append(getCacheFlags(),
+		getMaxChartSizeFlag(),
 		components.BoolFlag{
 			Name:  "dry-run",
 			Description: "Set to true to disable communication with JFrog Distribution.",
@@ -148,6 +188,78 @@ func getReleaseBundleTranslateChartFlags() []components.Flag {
 			Name:  "repo",
 			Description: "A repository name at source Artifactory to store release bundle artifacts in. If not provided, Artifactory will use the default one.",
 		},
+		getRepoMappingsFlag(),
+		components.BoolFlag{
+			Name:  "origin-props",
+			Description: "Set to true to add properties describing the origin of each artifact to the release bundle: the chart it's for, its parent chart, the values profile, and the original reference and registry of images.",
+		},
+		components.StringFlag{
+			Name:  "platforms",
+			Description: "[Optional] Comma separated platforms of multi-architecture images to include, such as linux/amd64,linux/arm64. All platforms are included by default.",
+		},
+		components.BoolFlag{
+			Name:  "referrers",
+			Description: "Set to true to add the signatures, attestations, SBOMs and OCI referrers of images to the release bundle.",
+		},
+		components.StringFlag{
+			Name:  "vulnerability-threshold",
+			Description: "[Optional] Scan images and charts for vulnerabilities, and refuse to create the release bundle if any has an issue of this severity or above: Low, Medium, High or Critical.",
+		},
+		components.StringFlag{
+			Name:  "scan-url",
+			Description: "[Optional] URL of Xray, or of a service with a compatible component summary API, scanning for vulnerabilities. By default, Xray on the platform of Artifactory.",
+		},
+		components.BoolFlag{
+			Name:  "vulnerabilities-in-release-notes",
+			Description: "Set to true to add the vulnerabilities found to the release notes.",
+		},
+		components.StringFlag{
+			Name:  "policy",
+			Description: "[Optional] Path to a YAML policy file, with rules the images and charts of the release bundle are checked against.",
+		},
+		components.StringFlag{
+			Name:  "resolve-workers",
+			Description: "The number of artifacts searched for in Artifactory at once.",
+			DefaultValue: defaultResolveWorkers,
+		},
+		components.StringFlag{
+			Name:  "sbom",
+			Description: "[Optional] Generic repository or path to upload a CycloneDX SBOM of the release bundle to, which is added to the release bundle.",
+		},
+		components.BoolFlag{
+			Name:  "verify-signatures",
+			Description: "Set to true to verify the cosign signatures of images before creating the release bundle, blocking images which aren't signed.",
+		},
+		components.StringFlag{
+			Name:  "signature-key",
+			Description: "[Optional] Path to the PEM encoded public key verifying the signatures of images.",
+		},
+		components.StringFlag{
+			Name:  "certificate-roots",
+			Description: "[Optional] Path to the PEM encoded root certificates of keyless signatures, when verifying signatures without --signature-key.",
+		},
+		components.StringFlag{
+			Name:  "certificate-identity",
+			Description: "[Optional] The identity, email or URI, keyless signatures must be issued to.",
+		},
+		components.StringFlag{
+			Name:  "certificate-oidc-issuer",
+			Description: "[Optional] The OIDC issuer keyless signatures must be issued by.",
+		},
+		components.BoolFlag{
+			Name:  "verify-digests",
+			Description: "Set to true to fail if the digest of an image changed between resolving it and creating the release bundle version.",
+		},
+		components.StringFlag{
+			Name:  "props",
+			Description: "List of properties in the form of \"key1=value1;key2=value2,...\" to add to every artifact of the release bundle.",
+		})
+}
+
+func getRepoMappingsFlag() components.StringFlag {
+	return components.StringFlag{
+		Name:        "repo-mappings",
+		Description: "Semicolon-separated list of repository mappings, as in source=target, or type:source=target to only map artifacts of a package type, such as docker or helm. Artifacts of the source repository are stored in the target repository on the edge nodes.",
 	}
 }
 
@@ -161,12 +273,16 @@ func releaseBundleTranslateChartCmd(c *components.Context) error {
 	if err != nil {
 		return err
 	}
+	options, err := createBundleOptions(c)
+	if err != nil {
+		return err
+	}
 	translateChartCmd := NewTranslateChartCommand()
 	rtDetails, err := createArtifactoryDetailsByFlags(c)
 	if err != nil {
 		return err
 	}
-	translateChartCmd.SetRtDetails(rtDetails).SetReleaseBundleCreateParams(params).SetSourceChartPath(chartpath).SetDockerRepo(dockerrepo).SetDryRun(c.GetBoolFlagValue("dry-run"))
+	translateChartCmd.SetRtDetails(rtDetails).SetReleaseBundleCreateParams(params).SetBundleOptions(options).SetSourceChartPath(chartpath).SetDockerRepo(dockerrepo).SetExtendBundle(c.GetStringFlagValue("extend")).SetRelocate(c.GetStringFlagValue("relocate")).SetRelocatedVersion(c.GetStringFlagValue("relocated-version")).SetEdgeValues(c.GetStringFlagValue("edge-values")).SetEdgeValuesPath(c.GetStringFlagValue("edge-values-path")).SetDryRun(c.GetBoolFlagValue("dry-run"))
 	return rtcommands.Exec(translateChartCmd)
 }
 
@@ -191,6 +307,58 @@ func createReleaseBundleCreateUpdateParams(c *components.Context, bundleName, bu
 	return releaseBundleParams, nil
 }
 
+func createBundleOptions(c *components.Context) (bundleOptions, error) {
+	mappings, err := parseRepoMappings(c.GetStringFlagValue("repo-mappings"))
+	if err != nil {
+		return bundleOptions{}, err
+	}
+	props, err := parseProps(c.GetStringFlagValue("props"))
+	if err != nil {
+		return bundleOptions{}, err
+	}
+	platforms, err := parsePlatforms(c.GetStringFlagValue("platforms"))
+	if err != nil {
+		return bundleOptions{}, err
+	}
+	options := bundleOptions{repoMappings: mappings, originProps: c.GetBoolFlagValue("origin-props"), props: props,
+		verifyDigests: c.GetBoolFlagValue("verify-digests"), platforms: platforms, referrers: c.GetBoolFlagValue("referrers")}
+	if threshold := c.GetStringFlagValue("vulnerability-threshold"); threshold != "" {
+		options.vulnerabilityThreshold, err = parseSeverity(threshold)
+		if err != nil {
+			return bundleOptions{}, err
+		}
+		options.scanUrl = c.GetStringFlagValue("scan-url")
+		options.vulnerabilityNotes = c.GetBoolFlagValue("vulnerabilities-in-release-notes")
+	}
+	if policyPath := c.GetStringFlagValue("policy"); policyPath != "" {
+		options.policy, err = loadPolicy(policyPath)
+		if err != nil {
+			return bundleOptions{}, err
+		}
+	}
+	if c.GetBoolFlagValue("verify-signatures") {
+		options.signatures, err = newSignatureVerifier(c.GetStringFlagValue("signature-key"), c.GetStringFlagValue("certificate-roots"),
+			c.GetStringFlagValue("certificate-identity"), c.GetStringFlagValue("certificate-oidc-issuer"))
+		if err != nil {
+			return bundleOptions{}, err
+		}
+	}
+	options.sbomPath = c.GetStringFlagValue("sbom")
+	options.cache, err = createCache(c)
+	if err != nil {
+		return bundleOptions{}, err
+	}
+	options.maxChartSize, err = parseMaxChartSize(c.GetStringFlagValue("max-chart-size"))
+	if err != nil {
+		return bundleOptions{}, err
+	}
+	options.resolveWorkers, err = parseWorkers(c.GetStringFlagValue("resolve-workers"), defaultResolveWorkers)
+	if err != nil {
+		return bundleOptions{}, err
+	}
+	return options, nil
+}
+
 func populateReleaseNotesSyntax(c *components.Context) (distributionServicesUtils.ReleaseNotesSyntax, error) {
 	// If release notes syntax is set, use it
 	releaseNotexSyntax := c.GetStringFlagValue("release-notes-syntax")
@@ -387,6 +555,11 @@ func (tc *TranslateChartCommand) SetReleaseBundleCreateParams(params distributio
 	return tc
 }
 
+func (tc *TranslateChartCommand) SetBundleOptions(options bundleOptions) *TranslateChartCommand {
+	tc.bundleOptions = options
+	return tc
+}
+
 func (tc *TranslateChartCommand) SetSourceChartPath(sourceChartPath string) *TranslateChartCommand {
 	tc.sourceChartPath = sourceChartPath
 	return tc
@@ -397,70 +570,152 @@ func (tc *TranslateChartCommand) SetDockerRepo(dockerRepo string) *TranslateChar
 	return tc
 }
 
+func (tc *TranslateChartCommand) SetExtendBundle(extendBundle string) *TranslateChartCommand {
+	tc.extendBundle = extendBundle
+	return tc
+}
+
+func (tc *TranslateChartCommand) SetRelocate(relocate string) *TranslateChartCommand {
+	tc.relocate = relocate
+	return tc
+}
+
+func (tc *TranslateChartCommand) SetRelocatedVersion(relocatedVersion string) *TranslateChartCommand {
+	tc.relocatedVersion = relocatedVersion
+	return tc
+}
+
+func (tc *TranslateChartCommand) SetEdgeValues(edgeValues string) *TranslateChartCommand {
+	tc.edgeValues = edgeValues
+	return tc
+}
+
+func (tc *TranslateChartCommand) SetEdgeValuesPath(edgeValuesPath string) *TranslateChartCommand {
+	tc.edgeValuesPath = edgeValuesPath
+	return tc
+}
+
 func (tc *TranslateChartCommand) SetDryRun(dryRun bool) *TranslateChartCommand {
 	tc.dryRun = dryRun
 	return tc
 }
 
 func (tc *TranslateChartCommand) Run() error {
-	body, err := readFileFromArtifactory(tc.rtDetails, tc.sourceChartPath)
+	chrt, checksum, err := loadChartArchive(tc.rtDetails, tc.sourceChartPath, tc.bundleOptions.cache, tc.bundleOptions.maxChartSize)
 	if err != nil {
 		return err
 	}
-	defer body.Close()
-	chrt, err := chartutil.LoadArchive(body)
+	// The chart is relocated as in its archive, before rendering changes it.
+	source := copyChart(chrt)
+	_, expected, err := createFilespec(chrt, extractRepo(tc.sourceChartPath), tc.dockerRepo)
 	if err != nil {
 		return err
 	}
-	specstr, expected, err := createFilespec(chrt, extractRepo(tc.sourceChartPath), tc.dockerRepo)
-	if err != nil {
-		return err
+	if tc.edgeValues != "" {
+		expected, err = tc.addEdgeValues(chrt, expected)
+		if err != nil {
+			return err
+		}
 	}
-	specfiles := new(spec.SpecFiles)
-	err = json.Unmarshal([]byte(specstr), specfiles)
-	if err != nil {
-		return err
+	if tc.relocate != "" {
+		expected, err = tc.relocateChart(source, expected)
+		if err != nil {
+			return err
+		}
 	}
-	createBundle := distribution.NewReleaseBundleCreateCommand()
-	createBundle.SetRtDetails(tc.rtDetails)
-	createBundle.SetReleaseBundleCreateParams(tc.releaseBundlesParams)
-	createBundle.SetSpec(specfiles)
-	createBundle.SetDryRun(tc.dryRun)
-	err = createBundle.Run()
-	if err != nil {
-		return err
+	params := tc.releaseBundlesParams
+	if tc.extendBundle != "" {
+		name, version, err := parseBundleNameAndVersion(tc.extendBundle)
+		if err != nil {
+			return err
+		}
+		original, err := getReleaseBundleVersion(tc.rtDetails, name, version)
+		if err != nil {
+			return err
+		}
+		params, expected, err = extendReleaseBundle(params, expected, original)
+		if err != nil {
+			return err
+		}
 	}
-	actual, err := checkExisting(tc.rtDetails, specfiles)
+	options := tc.bundleOptions
+	options.chartChecksums = map[string]string{tc.sourceChartPath: checksum}
+	return createBundleAndReport(tc.rtDetails, params, options, expected, tc.dryRun)
+}
+
+// addEdgeValues uploads a values file setting the images of chrt to their
+// location on the edge nodes, and adds it to expected.
+func (tc *TranslateChartCommand) addEdgeValues(chrt *chart.Chart, expected []bundleArtifact) ([]bundleArtifact, error) {
+	content, remaining, err := createEdgeValues(chrt, tc.edgeValues)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	for _, image := range remaining {
+		log.Warn("The edge values file does not set the image " + image + ", which doesn't come from values.")
 	}
-	missing := make([]string, 0)
-	fmt.Println("Found:")
-	for _, name := range expected {
-		line := "/" + name
-		found := false
-		if !strings.HasSuffix(line, ".tgz") {
-			line = strings.ReplaceAll(line, ":", "/") + "/"
+	uploadPath := tc.edgeValuesPath
+	if uploadPath == "" || strings.HasSuffix(uploadPath, "/") {
+		dir := uploadPath
+		if dir == "" {
+			dir = path.Dir(strings.TrimPrefix(tc.sourceChartPath, "/"))
 		}
-		for _, path := range actual {
-			if strings.Contains(path, line) {
-				fmt.Println("- " + name)
-				found = true
-				break
-			}
+		uploadPath = path.Join(dir, chrt.Metadata.Name+"-"+chrt.Metadata.Version+edgeValuesSuffix)
+	}
+	if tc.dryRun {
+		log.Info("Dry run: the edge values file would be uploaded to " + uploadPath + ":\n" + string(content))
+	} else {
+		file, err := ioutil.TempFile("", "values-edge")
+		if err != nil {
+			return nil, errorutils.CheckError(err)
+		}
+		defer os.Remove(file.Name())
+		_, err = file.Write(content)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
 		}
-		if !found {
-			missing = append(missing, name)
+		if err != nil {
+			return nil, errorutils.CheckError(err)
+		}
+		if err := uploadFileToArtifactory(tc.rtDetails, file.Name(), uploadPath); err != nil {
+			return nil, err
 		}
 	}
-	fmt.Println("Missing:")
-	if len(missing) <= 0 {
-		missing = append(missing, "none")
+	values := bundleArtifact{name: path.Base(uploadPath), packageType: "generic", source: uploadPath, patterns: []string{uploadPath}}
+	return append(expected, values), nil
+}
+
+// relocateChart relocates the images of chrt, uploads the relocated chart next
+// to the original one, and puts it in expected instead of the original.
+func (tc *TranslateChartCommand) relocateChart(chrt *chart.Chart, expected []bundleArtifact) ([]bundleArtifact, error) {
+	helmrepo := extractRepo(tc.sourceChartPath)
+	original := helmrepo + "/" + chrt.Metadata.Name + "-" + chrt.Metadata.Version + ".tgz"
+	version := tc.relocatedVersion
+	if version == "" {
+		version = chrt.Metadata.Version + relocatedVersionSuffix
+	}
+	remaining, err := relocateChart(chrt, tc.relocate, version)
+	if err != nil {
+		return nil, err
+	}
+	for _, image := range remaining {
+		log.Warn("Could not relocate the image " + image + ", which is set neither by values nor as is in templates.")
+	}
+	dir, err := ioutil.TempDir("", "relocated-chart")
+	if err != nil {
+		return nil, errorutils.CheckError(err)
+	}
+	defer os.RemoveAll(dir)
+	archive, err := chartutil.Save(chrt, dir)
+	if err != nil {
+		return nil, errorutils.CheckError(err)
 	}
-	for _, line := range missing {
-		fmt.Println("- " + line)
+	uploadPath := path.Join(path.Dir(strings.TrimPrefix(tc.sourceChartPath, "/")), filepath.Base(archive))
+	if tc.dryRun {
+		log.Info("Dry run: the relocated chart would be uploaded to " + uploadPath + ".")
+	} else if err := uploadFileToArtifactory(tc.rtDetails, archive, uploadPath); err != nil {
+		return nil, err
 	}
-	return nil
+	return replaceChartArtifact(expected, original, uploadPath), nil
 }
 
 func (tc *TranslateChartCommand) RtDetails() (*config.ArtifactoryDetails, error) {
@@ -480,75 +735,124 @@ func extractRepo(path string) string {
 
 func readFileFromArtifactory(artDetails *config.ArtifactoryDetails, downloadPath string) (io.ReadCloser, error) {
 	downloadUrl := urlAppend(artDetails.Url, downloadPath)
-	auth, err := artDetails.CreateArtAuthConfig()
+	client, auth, err := createArtifactoryClient(artDetails)
 	if err != nil {
 		return nil, err
 	}
+	httpClientDetails := auth.CreateHttpClientDetails()
+	body, resp, err := client.ReadRemoteFile(downloadUrl, &httpClientDetails)
+	if err == nil && resp.StatusCode != http.StatusOK {
+		err = errorutils.CheckError(errors.New(resp.Status + " received when attempting to download " + downloadUrl))
+	}
+	return body, err
+}
+
+// uploadFileToArtifactory uploads a local file to uploadPath, as in repo/path,
+// letting Artifactory verify its SHA-256 checksum.
+func uploadFileToArtifactory(artDetails *config.ArtifactoryDetails, localPath, uploadPath string) error {
+	uploadUrl := urlAppend(artDetails.Url, uploadPath)
+	client, auth, err := createArtifactoryClient(artDetails)
+	if err != nil {
+		return err
+	}
+	checksum, err := fileChecksum(localPath)
+	if err != nil {
+		return errorutils.CheckError(err)
+	}
+	httpClientDetails := auth.CreateHttpClientDetails()
+	servicesutils.AddHeader("X-Checksum-Sha256", checksum, &httpClientDetails.Headers)
+	resp, _, err := client.UploadFile(localPath, uploadUrl, "", &httpClientDetails, 3, nil)
+	if err == nil && resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		err = errorutils.CheckError(errors.New(resp.Status + " received when attempting to upload " + uploadUrl))
+	}
+	return err
+}
+
+func createArtifactoryClient(artDetails *config.ArtifactoryDetails) (*rthttpclient.ArtifactoryHttpClient, auth.ServiceDetails, error) {
+	artAuth, err := artDetails.CreateArtAuthConfig()
+	if err != nil {
+		return nil, nil, err
+	}
 	securityDir, err := coreutils.GetJfrogSecurityDir()
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	client, err := rthttpclient.ArtifactoryClientBuilder().
 		SetCertificatesPath(securityDir).
 		SetInsecureTls(artDetails.InsecureTls).
-		SetServiceDetails(&auth).
+		SetServiceDetails(&artAuth).
 		Build()
-	if err != nil {
-		return nil, err
-	}
-	httpClientDetails := auth.CreateHttpClientDetails()
-	body, resp, err := client.ReadRemoteFile(downloadUrl, &httpClientDetails)
-	if err == nil && resp.StatusCode != http.StatusOK {
-		err = errorutils.CheckError(errors.New(resp.Status + " received when attempting to download " + downloadUrl))
-	}
-	return body, err
+	return client, artAuth, err
 }
 
-func createFilespec(chrt *chart.Chart, helmrepo, dockerrepo string) (string, []string, error) {
-	flist := make([]string, 0)
+func createFilespec(chrt *chart.Chart, helmrepo, dockerrepo string) (*spec.SpecFiles, []bundleArtifact, error) {
 	files, err := renderutil.Render(chrt, &chart.Config{Raw: "{}"}, renderutil.Options{})
 	if err != nil {
-		return "", flist, err
-	}
-	spec := "{\"files\":["
-	lines := extractImages(files)
-	for _, line := range sortStringMap(lines) {
-		splits := strings.SplitN(line, "/", 2)
-		image := splits[len(splits)-1]
-		cname := strings.ReplaceAll(splits[len(splits)-1], ":", "/") + "/"
-		path1, _ := json.Marshal(dockerrepo+"/"+cname)
-		path2, _ := json.Marshal(dockerrepo+"/*/"+cname)
-		spec = spec + "{\"pattern\":" + string(path1) + "},"
-		spec = spec + "{\"pattern\":" + string(path2) + "},"
-		flist = append(flist, image)
+		return nil, make([]bundleArtifact, 0), err
+	}
+	charts := map[string]string{}
+	addChartArchives(charts, chrt, helmrepo)
+	origins := originIndex{}
+	origins.addChartOrigins(chrt, files, helmrepo, defaultValuesProfile)
+	flist := attachOrigins(imageAndChartArtifacts(extractImages(files), charts, dockerrepo), origins)
+	return createSpecFiles(flist), flist, nil
+}
+
+// buildFilespec creates a file spec matching the given Docker images and Helm
+// chart archives. The charts map is keyed by "<helm repo>/<archive name>".
+func buildFilespec(images map[string]string, charts map[string]string, dockerrepo string) (*spec.SpecFiles, []bundleArtifact) {
+	artifacts := imageAndChartArtifacts(images, charts, dockerrepo)
+	return createSpecFiles(artifacts), artifacts
+}
+
+func imageAndChartArtifacts(images map[string]string, charts map[string]string, dockerrepo string) []bundleArtifact {
+	artifacts := make([]bundleArtifact, 0)
+	for _, line := range sortStringMap(images) {
+		artifacts = append(artifacts, newImageArtifact(line, parseChartImageReference(line), dockerrepo))
+	}
+	for _, key := range sortedKeys(charts) {
+		helmrepo := strings.SplitN(key, "/", 2)[0]
+		artifact := newBundleArtifact(charts[key], helmrepo, charts[key])
+		artifact.packageType = "helm"
+		artifact.source = key
+		artifacts = append(artifacts, artifact)
 	}
+	return artifacts
+}
+
+// addChartArchives adds the archive of chrt and of each of its dependencies,
+// all expected to be stored in helmrepo.
+func addChartArchives(charts map[string]string, chrt *chart.Chart, helmrepo string) {
 	deps := map[string]*chart.Chart{}
 	crawlRequirements(deps, chrt)
 	for _, c := range sortChartMap(deps) {
 		cname := c.Metadata.Name + "-" + c.Metadata.Version + ".tgz"
-		path1, _ := json.Marshal(helmrepo+"/"+cname)
-		path2, _ := json.Marshal(helmrepo+"/*/"+cname)
-		spec = spec + "{\"pattern\":" + string(path1) + "},"
-		spec = spec + "{\"pattern\":" + string(path2) + "},"
-		flist = append(flist, cname)
+		charts[helmrepo+"/"+cname] = cname
 	}
-	spec = spec[:len(spec)-1]
-	spec = spec + "]}"
-	return spec, flist, nil
 }
 
-func checkExisting(rtDetails *config.ArtifactoryDetails, spec *spec.SpecFiles) ([]string, error) {
-	flist := make([]string, 0)
+// searchExisting searches for spec, returning the results with their
+// properties.
+func searchExisting(rtDetails *config.ArtifactoryDetails, spec *spec.SpecFiles) ([]rtutils.SearchResult, error) {
+	found := make([]rtutils.SearchResult, 0)
 	cmd := generic.NewSearchCommand()
 	cmd.SetRtDetails(rtDetails).SetSpec(spec)
 	results, err := cmd.Search()
 	if err != nil {
-		return flist, err
+		return found, err
+	}
+	for result := new(rtutils.SearchResult); results.NextRecord(result) == nil; result = new(rtutils.SearchResult) {
+		found = append(found, *result)
 	}
-	for result := new(servicesutils.ResultItem); results.NextRecord(result) == nil; result = new(servicesutils.ResultItem) {
-		flist = append(flist, result.Path)
+	return found, nil
+}
+
+func resultPaths(results []rtutils.SearchResult) []string {
+	paths := make([]string, 0)
+	for _, result := range results {
+		paths = append(paths, result.Path)
 	}
-	return flist, nil
+	return paths
 }
 
 func sortStringMap(in map[string]string) []string {
@@ -577,6 +881,15 @@ func sortChartMap(in map[string]*chart.Chart) []*chart.Chart {
 	return vals
 }
 
+func sortedKeys(in map[string]string) []string {
+	keys := make([]string, 0, len(in))
+	for k := range in {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 func urlAppend(url, path string) string {
 	if url[len(url)-1] != '/' {
 		url = url + "/"
@@ -1,8 +1,12 @@
 package commands
 
 import (
+	"encoding/json"
 	"testing"
+	"reflect"
 	"k8s.io/helm/pkg/chartutil"
+	"github.com/jfrog/jfrog-cli-core/artifactory/spec"
+	servicesutils "github.com/jfrog/jfrog-client-go/artifactory/services/utils"
 )
 
 func TestHelmToFilespec1(t *testing.T) {
@@ -11,10 +15,11 @@ func TestHelmToFilespec1(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Error loading test chart: %s\n", err)
 	}
-	spec, _, err := createFilespec(chrt, "testhelmrepo", "testdockerrepo")
+	specfiles, _, err := createFilespec(chrt, "testhelmrepo", "testdockerrepo")
 	if err != nil {
 		t.Fatalf("Error generating filespec: %s\n", err)
 	}
+	spec := serializeSpecFiles(specfiles)
 	if spec != expected {
 		t.Fatalf("Generated spec is incorrect. Expected:\n%s\nGot:\n%s\n", expected, spec)
 	}
@@ -26,11 +31,52 @@ func TestHelmToFilespec2(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Error loading test chart: %s\n", err)
 	}
-	spec, _, err := createFilespec(chrt, "testhelmrepo", "testdockerrepo")
+	specfiles, _, err := createFilespec(chrt, "testhelmrepo", "testdockerrepo")
 	if err != nil {
 		t.Fatalf("Error generating filespec: %s\n", err)
 	}
+	spec := serializeSpecFiles(specfiles)
 	if spec != expected {
 		t.Fatalf("Generated spec is incorrect. Expected:\n%s\nGot:\n%s\n", expected, spec)
 	}
 }
+
+func TestChartImagePaths(t *testing.T) {
+	// The first component of an image a chart uses is dropped, whether it's a registry or not.
+	expected := "{\"files\":[{\"pattern\":\"testdockerrepo/alpine/3.10/\"},{\"pattern\":\"testdockerrepo/*/alpine/3.10/\"},{\"pattern\":\"testdockerrepo/postgresql/11/\"},{\"pattern\":\"testdockerrepo/*/postgresql/11/\"},{\"pattern\":\"testdockerrepo/bitnami/redis/6.0.8/\"},{\"pattern\":\"testdockerrepo/*/bitnami/redis/6.0.8/\"}]}"
+	images := map[string]string{
+		"alpine:3.10":                  "alpine:3.10",
+		"bitnami/postgresql:11":        "bitnami/postgresql:11",
+		"docker.io/bitnami/redis:6.0.8": "docker.io/bitnami/redis:6.0.8",
+	}
+	specfiles, artifacts := buildFilespec(images, map[string]string{}, "testdockerrepo")
+	spec := serializeSpecFiles(specfiles)
+	if spec != expected {
+		t.Fatalf("Generated spec is incorrect. Expected:\n%s\nGot:\n%s\n", expected, spec)
+	}
+	if artifacts[1].name != "postgresql:11" || artifacts[1].source != "bitnami/postgresql:11" {
+		t.Fatalf("Unexpected artifact %+v\n", artifacts[1])
+	}
+}
+
+func TestSerializeSpecFiles(t *testing.T) {
+	if spec := serializeSpecFiles(createSpecFiles([]bundleArtifact{})); spec != "{\"files\":[]}" {
+		t.Fatalf("Generated empty spec is incorrect: %s\n", spec)
+	}
+	file := spec.File{Pattern: "helm-local/charts/*.tgz", Exclusions: []string{"*-rc*"}, Target: "helm-edge/charts/",
+		Props: "approved=true", Recursive: "false"}
+	query := spec.File{Aql: servicesutils.Aql{ItemsFind: "{\"repo\":\"generic-local\"}"}}
+	specfiles := createSpecFiles([]bundleArtifact{{name: "charts", files: []spec.File{file, query}}})
+	expected := "{\"files\":[{\"pattern\":\"helm-local/charts/*.tgz\",\"exclusions\":[\"*-rc*\"],\"target\":\"helm-edge/charts/\",\"props\":\"approved=true\",\"recursive\":\"false\"},{\"aql\":{\"items.find\":{\"repo\":\"generic-local\"}}}]}"
+	serialized := serializeSpecFiles(specfiles)
+	if serialized != expected {
+		t.Fatalf("Serialized spec is incorrect. Expected:\n%s\nGot:\n%s\n", expected, serialized)
+	}
+	parsed := new(spec.SpecFiles)
+	if err := json.Unmarshal([]byte(serialized), parsed); err != nil {
+		t.Fatalf("Error parsing serialized spec: %s\n", err)
+	}
+	if !reflect.DeepEqual(parsed, specfiles) {
+		t.Fatalf("Parsed spec is incorrect. Expected:\n%+v\nGot:\n%+v\n", specfiles, parsed)
+	}
+}
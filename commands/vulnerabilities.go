@@ -0,0 +1,216 @@
+package commands
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/jfrog/jfrog-cli-core/utils/config"
+	artifactoryUtils "github.com/jfrog/jfrog-client-go/artifactory/services/utils"
+	clientutils "github.com/jfrog/jfrog-client-go/utils"
+	"github.com/jfrog/jfrog-client-go/utils/errorutils"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// severityRanks orders the severities of Xray issues.
+var severityRanks = map[string]int{"unknown": 0, "information": 0, "low": 1, "medium": 2, "high": 3, "critical": 4}
+
+var componentIdScheme = regexp.MustCompile(`^[a-z]+://`)
+
+// vulnerabilityScanner returns the issues of components, given as Xray
+// component ids such as docker://alpine:3.10, by component id.
+type vulnerabilityScanner func(componentIds []string) (map[string][]vulnerabilityIssue, error)
+
+// vulnerabilityIssue is an issue of a component, as in the component summary
+// of Xray.
+type vulnerabilityIssue struct {
+	IssueId  string `json:"issue_id"`
+	Summary  string `json:"summary"`
+	Severity string `json:"severity"`
+	Cves     []struct {
+		Cve string `json:"cve"`
+	} `json:"cves"`
+}
+
+type componentSummaryRequest struct {
+	ComponentDetails []componentDetails `json:"component_details"`
+}
+
+type componentDetails struct {
+	ComponentId string `json:"component_id"`
+}
+
+type componentSummaryResponse struct {
+	Artifacts []struct {
+		General struct {
+			ComponentId string `json:"component_id"`
+		} `json:"general"`
+		Issues []vulnerabilityIssue `json:"issues"`
+	} `json:"artifacts"`
+}
+
+// vulnerabilityFinding lists the issues of an artifact.
+type vulnerabilityFinding struct {
+	artifact string
+	issues   []vulnerabilityIssue
+}
+
+// defaultScanUrl returns the URL of Xray on the platform of the Artifactory at
+// rtUrl, as in https://example.com/xray/.
+func defaultScanUrl(rtUrl string) string {
+	return strings.TrimSuffix(clientutils.AddTrailingSlashIfNeeded(rtUrl), "artifactory/") + "xray/"
+}
+
+// newXrayScanner creates a scanner requesting the component summary API of
+// Xray, or of a service compatible with it, at scanUrl, with the credentials
+// of rtDetails.
+func newXrayScanner(rtDetails *config.ArtifactoryDetails, scanUrl string) vulnerabilityScanner {
+	return func(componentIds []string) (map[string][]vulnerabilityIssue, error) {
+		request := componentSummaryRequest{ComponentDetails: make([]componentDetails, 0)}
+		for _, id := range componentIds {
+			request.ComponentDetails = append(request.ComponentDetails, componentDetails{ComponentId: id})
+		}
+		content, err := json.Marshal(request)
+		if err != nil {
+			return nil, errorutils.CheckError(err)
+		}
+		client, auth, err := createArtifactoryClient(rtDetails)
+		if err != nil {
+			return nil, err
+		}
+		httpClientDetails := auth.CreateHttpClientDetails()
+		artifactoryUtils.SetContentType("application/json", &httpClientDetails.Headers)
+		summaryUrl := clientutils.AddTrailingSlashIfNeeded(scanUrl) + "api/v1/summary/component"
+		resp, body, err := client.SendPost(summaryUrl, content, &httpClientDetails)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			return nil, errorutils.CheckError(errors.New(resp.Status + " received when requesting " + summaryUrl + "\n" + clientutils.IndentJson(body)))
+		}
+		summary := &componentSummaryResponse{}
+		if err := json.Unmarshal(body, summary); err != nil {
+			return nil, errorutils.CheckError(err)
+		}
+		issues := map[string][]vulnerabilityIssue{}
+		for _, artifact := range summary.Artifacts {
+			// Xray may leave the package type out of the component ids it returns.
+			id := componentIdScheme.ReplaceAllString(artifact.General.ComponentId, "")
+			issues[id] = append(issues[id], artifact.Issues...)
+		}
+		return issues, nil
+	}
+}
+
+// componentId returns the Xray component id of an image or of a chart archive
+// whose chart was read, or an empty string for other artifacts.
+func componentId(artifact bundleArtifact) string {
+	switch artifact.packageType {
+	case "docker":
+		if artifact.source != "" {
+			return "docker://" + parseImageReference(artifact.source).name()
+		}
+	case "helm":
+		if origin, ok := chartArchiveOrigin(artifact); ok {
+			version := strings.TrimSuffix(strings.TrimPrefix(artifact.name, origin.chart+"-"), ".tgz")
+			return "helm://" + origin.chart + ":" + version
+		}
+	}
+	return ""
+}
+
+// scanVulnerabilities returns the issues of the images and charts among
+// artifacts.
+func scanVulnerabilities(artifacts []bundleArtifact, scan vulnerabilityScanner) ([]vulnerabilityFinding, error) {
+	ids := make([]string, 0)
+	for _, artifact := range artifacts {
+		if id := componentId(artifact); id != "" && !containsString(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	findings := make([]vulnerabilityFinding, 0)
+	if len(ids) == 0 {
+		return findings, nil
+	}
+	issues, err := scan(ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, artifact := range artifacts {
+		if id := componentId(artifact); id != "" {
+			findings = append(findings, vulnerabilityFinding{artifact: artifact.name, issues: issues[componentIdScheme.ReplaceAllString(id, "")]})
+		}
+	}
+	return findings, nil
+}
+
+// parseSeverity checks a severity threshold, such as High.
+func parseSeverity(severity string) (string, error) {
+	if severityRanks[strings.ToLower(severity)] == 0 {
+		return "", errorutils.CheckError(errors.New("The severity threshold must be one of: Low, Medium, High or Critical."))
+	}
+	return strings.ToLower(severity), nil
+}
+
+// formatFindings describes the issues of each artifact, one line per artifact.
+func formatFindings(findings []vulnerabilityFinding) []string {
+	lines := make([]string, 0)
+	for _, finding := range findings {
+		issues := make([]string, 0)
+		for _, issue := range finding.issues {
+			description := issue.Severity + " " + issue.IssueId
+			cves := make([]string, 0)
+			for _, cve := range issue.Cves {
+				if cve.Cve != "" {
+					cves = append(cves, cve.Cve)
+				}
+			}
+			if len(cves) > 0 {
+				description = description + " (" + strings.Join(cves, ", ") + ")"
+			}
+			issues = append(issues, description)
+		}
+		if len(issues) == 0 {
+			issues = append(issues, "none")
+		}
+		lines = append(lines, finding.artifact+": "+strings.Join(issues, ", "))
+	}
+	return lines
+}
+
+// reportVulnerabilities prints the issues of each artifact, and fails if any
+// of them has a severity of threshold or above.
+func reportVulnerabilities(out io.Writer, findings []vulnerabilityFinding, threshold string) error {
+	fmt.Fprintln(out, "Vulnerabilities:")
+	for _, line := range formatFindings(findings) {
+		fmt.Fprintln(out, "- "+line)
+	}
+	blocked := make([]string, 0)
+	for _, finding := range findings {
+		for _, issue := range finding.issues {
+			if severityRanks[strings.ToLower(issue.Severity)] >= severityRanks[threshold] {
+				blocked = append(blocked, finding.artifact)
+				break
+			}
+		}
+	}
+	if len(blocked) > 0 {
+		return errorutils.CheckError(errors.New("Vulnerabilities of " + threshold + " severity or above were found in " + strings.Join(blocked, ", ") + "."))
+	}
+	return nil
+}
+
+// addFindingsToReleaseNotes appends the issues of each artifact to the
+// release notes.
+func addFindingsToReleaseNotes(notes string, findings []vulnerabilityFinding) string {
+	lines := []string{"Vulnerabilities:"}
+	for _, line := range formatFindings(findings) {
+		lines = append(lines, "- "+line)
+	}
+	if notes != "" {
+		notes = strings.TrimRight(notes, "\n") + "\n\n"
+	}
+	return notes + strings.Join(lines, "\n") + "\n"
+}
@@ -0,0 +1,96 @@
+package commands
+
+import (
+	"encoding/json"
+	"github.com/jfrog/jfrog-cli-core/utils/config"
+	"io/ioutil"
+	"k8s.io/helm/pkg/chartutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// componentSummaryStandIn serves the component summary API, with an issue for
+// alpine.
+func componentSummaryStandIn(t *testing.T, requested *[]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/xray/api/v1/summary/component" {
+			http.NotFound(w, r)
+			return
+		}
+		request := &componentSummaryRequest{}
+		if err := json.NewDecoder(r.Body).Decode(request); err != nil {
+			t.Error(err)
+		}
+		response := `{"artifacts":[`
+		for i, component := range request.ComponentDetails {
+			*requested = append(*requested, component.ComponentId)
+			if i > 0 {
+				response += ","
+			}
+			issues := ""
+			if component.ComponentId == "docker://alpine:3.10" {
+				issues = `{"issue_id":"XRAY-1","summary":"Overflow","severity":"High","cves":[{"cve":"CVE-2020-1"}]},{"issue_id":"XRAY-2","severity":"Low"}`
+			}
+			response += `{"general":{"component_id":"` + strings.TrimPrefix(component.ComponentId, "docker://") + `"},"issues":[` + issues + `]}`
+		}
+		w.Write([]byte(response + "]}"))
+	}))
+}
+
+func TestScanVulnerabilities(t *testing.T) {
+	requested := make([]string, 0)
+	server := componentSummaryStandIn(t, &requested)
+	defer server.Close()
+	chrt, err := chartutil.Load("testdata/artifactory-jcr-2.2.0.tgz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, artifacts, err := createFilespec(chrt, "helm-local", "docker-local")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rtDetails := &config.ArtifactoryDetails{Url: server.URL + "/artifactory/"}
+	findings, err := scanVulnerabilities(artifacts, newXrayScanner(rtDetails, defaultScanUrl(rtDetails.Url)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedIds := []string{
+		"docker://alpine:3.10",
+		"docker://bitnami/postgresql:9.6.17-debian-10-r21",
+		"docker://jfrog/artifactory-jcr:7.4.1",
+		"docker://jfrog/nginx-artifactory-pro:7.4.1",
+		"helm://artifactory:9.4.0",
+		"helm://artifactory-jcr:2.2.0",
+		"helm://postgresql:8.7.3",
+	}
+	if !reflect.DeepEqual(requested, expectedIds) {
+		t.Errorf("Expected components:\n%s\ngot:\n%s", strings.Join(expectedIds, "\n"), strings.Join(requested, "\n"))
+	}
+	lines := formatFindings(findings)
+	if len(lines) != 7 || lines[0] != "alpine:3.10: High XRAY-1 (CVE-2020-1), Low XRAY-2" || lines[6] != "postgresql-8.7.3.tgz: none" {
+		t.Errorf("Unexpected findings:\n%s", strings.Join(lines, "\n"))
+	}
+
+	if err := reportVulnerabilities(ioutil.Discard, findings, "critical"); err != nil {
+		t.Errorf("Expected no critical vulnerability, got %v", err)
+	}
+	if err := reportVulnerabilities(ioutil.Discard, findings, "high"); err == nil || !strings.Contains(err.Error(), "found in alpine:3.10.") {
+		t.Errorf("Expected alpine to be blocked, got %v", err)
+	}
+	notes := addFindingsToReleaseNotes("# Platform\n", findings[:1])
+	if notes != "# Platform\n\nVulnerabilities:\n- alpine:3.10: High XRAY-1 (CVE-2020-1), Low XRAY-2\n" {
+		t.Errorf("Unexpected release notes:\n%s", notes)
+	}
+}
+
+func TestParseSeverity(t *testing.T) {
+	if severity, err := parseSeverity("High"); err != nil || severity != "high" {
+		t.Errorf("Unexpected severity %s, %v", severity, err)
+	}
+	if _, err := parseSeverity("Information"); err == nil {
+		t.Error("Expected a severity below Low to fail")
+	}
+}
@@ -13,7 +13,7 @@ func main() {
 func getApp() components.App {
 	app := components.App{}
 	app.Name = "release-bundle-generator"
-	app.Description = "Generate release bundles from other formats, such as Helm Charts."
+	app.Description = "Generate release bundles from other formats, such as Helm Charts, Argo CD applications, package lockfiles and Dockerfiles, or from several of them at once."
 	app.Version = "1.0.0"
 	app.Commands = getCommands()
 	return app
@@ -21,5 +21,13 @@ func getApp() components.App {
 
 func getCommands() []components.Command {
 	return []components.Command{
-		commands.GetReleaseBundleTranslateChartCommand()}
+		commands.GetReleaseBundleTranslateChartCommand(),
+		commands.GetReleaseBundleFromArgoCDCommand(),
+		commands.GetReleaseBundleFromLockfileCommand(),
+		commands.GetReleaseBundleFromDockerfileCommand(),
+		commands.GetReleaseBundleFromManifestCommand(),
+		commands.GetBatchCommand(),
+		commands.GetGenerateSpecCommand(),
+		commands.GetExportCommand(),
+		commands.GetImportCommand()}
 }